@@ -0,0 +1,229 @@
+package sparse
+
+import (
+	"fmt"
+	"iter"
+	"slices"
+	"sort"
+)
+
+const defaultChunkedArrayDataChunkSize = 256
+
+type ChunkedArrayDataOptions struct {
+	// Maximum number of items in single chunk. Zero means default value.
+	ChunkSize int
+}
+
+// Creates factory of ChunkedArrayData.
+func NewChunkedArrayDataFactory[Data any, Index any](opts ChunkedArrayDataOptions) SeriesDataFactory[Data, Index] {
+	if opts.ChunkSize <= 0 {
+		opts.ChunkSize = defaultChunkedArrayDataChunkSize
+	}
+
+	return func(
+		getIdx func(data *Data) Index,
+		idxCmp func(idx1, idx2 Index) int,
+		periodStart, periodEnd Index, data []Data,
+	) (SeriesData[Data, Index], error) {
+		s := &ChunkedArrayData[Data, Index]{
+			getIdx: getIdx,
+			idxCmp: idxCmp,
+			opts:   opts,
+		}
+
+		if err := s.Merge(data); err != nil {
+			return nil, err
+		}
+
+		return s, nil
+	}
+}
+
+var _ SeriesDataFactory[int, int] = NewChunkedArrayDataFactory[int, int](ChunkedArrayDataOptions{})
+
+// Storage in memory, which keeps data as list of sorted chunks of bounded size.
+// Merge and Delete rewrite only chunks, which intersect modified period, so small updates do not copy all the data.
+// Same as ArrayData by default, it allows multiple items with the same index, but it has no option to reject them.
+type ChunkedArrayData[Data any, Index any] struct {
+	getIdx func(data *Data) Index
+	idxCmp func(idx1, idx2 Index) int
+	opts   ChunkedArrayDataOptions
+
+	// Chunks are never modified in place
+	chunks [][]Data
+}
+
+func (s *ChunkedArrayData[Data, Index]) First(idx Index) (*Data, error) {
+	if len(s.chunks) == 0 {
+		return nil, nil
+	}
+
+	v := s.chunks[0][0]
+
+	return &v, nil
+}
+
+func (s *ChunkedArrayData[Data, Index]) Last(idx Index) (*Data, error) {
+	if len(s.chunks) == 0 {
+		return nil, nil
+	}
+
+	lastChunk := s.chunks[len(s.chunks)-1]
+	v := lastChunk[len(lastChunk)-1]
+
+	return &v, nil
+}
+
+func (s *ChunkedArrayData[Data, Index]) Get(periodStart, periodEnd Index) ([]Data, error) {
+	return s.get(periodStart, periodEnd, false), nil
+}
+
+func (s *ChunkedArrayData[Data, Index]) GetEndOpen(periodStart, periodEnd Index) ([]Data, error) {
+	return s.get(periodStart, periodEnd, true), nil
+}
+
+func (s *ChunkedArrayData[Data, Index]) get(periodStart, periodEnd Index, endOpen bool) []Data {
+	res := []Data{}
+
+	for chunk := range s.Chunks(periodStart, periodEnd) {
+		if endOpen {
+			chunk = chunk[:s.search(chunk, periodEnd, false)]
+		}
+
+		res = append(res, chunk...)
+	}
+
+	return res
+}
+
+// Returns parts of chunks, which lie within the period. Returned slices must not be modified.
+func (s *ChunkedArrayData[Data, Index]) Chunks(periodStart, periodEnd Index) iter.Seq[[]Data] {
+	lo, hi := s.findChunks(periodStart, periodEnd)
+	chunks := s.chunks[lo:hi]
+
+	return func(yield func([]Data) bool) {
+		for i, chunk := range chunks {
+			if i == 0 {
+				chunk = chunk[s.search(chunk, periodStart, false):]
+			}
+			if i == len(chunks)-1 {
+				chunk = chunk[:s.search(chunk, periodEnd, true)]
+			}
+
+			if len(chunk) != 0 && !yield(chunk) {
+				return
+			}
+		}
+	}
+}
+
+func (s *ChunkedArrayData[Data, Index]) Merge(data []Data) error {
+	if len(data) == 0 {
+		return nil
+	}
+
+	periodStart := s.getIdx(&data[0])
+	periodEnd := s.getIdx(&data[len(data)-1])
+
+	lo, hi := s.findChunks(periodStart, periodEnd)
+
+	s.rewrite(lo, hi, periodStart, periodEnd, data)
+
+	return nil
+}
+
+func (s *ChunkedArrayData[Data, Index]) Delete(periodStart, periodEnd Index) error {
+	lo, hi := s.findChunks(periodStart, periodEnd)
+	if lo == hi {
+		return nil
+	}
+
+	s.rewrite(lo, hi, periodStart, periodEnd, nil)
+
+	return nil
+}
+
+func (s *ChunkedArrayData[Data, Index]) All(periodStart, periodEnd Index) (iter.Seq[Data], error) {
+	chunks := s.Chunks(periodStart, periodEnd)
+
+	return func(yield func(Data) bool) {
+		for chunk := range chunks {
+			for i := range chunk {
+				if !yield(chunk[i]) {
+					return
+				}
+			}
+		}
+	}, nil
+}
+
+func (s *ChunkedArrayData[Data, Index]) Backward(periodStart, periodEnd Index) (iter.Seq[Data], error) {
+	chunks := slices.Collect(s.Chunks(periodStart, periodEnd))
+
+	return func(yield func(Data) bool) {
+		for c := len(chunks) - 1; c >= 0; c-- {
+			for i := len(chunks[c]) - 1; i >= 0; i-- {
+				if !yield(chunks[c][i]) {
+					return
+				}
+			}
+		}
+	}, nil
+}
+
+var _ IterableSeriesData[int, int] = &ChunkedArrayData[int, int]{}
+
+// Chunks are never modified in place, so snapshot just shares them.
+func (s *ChunkedArrayData[Data, Index]) Snapshot() (SeriesData[Data, Index], error) {
+	snapshot := *s
+	return &snapshot, nil
+}
+
+var _ SnapshotableSeriesData[int, int] = &ChunkedArrayData[int, int]{}
+
+func (s *ChunkedArrayData[Data, Index]) String() string {
+	return fmt.Sprintf("%v", s.chunks)
+}
+
+// Returns range [ lo ; hi ) of chunks, which intersect the period.
+func (s *ChunkedArrayData[Data, Index]) findChunks(periodStart, periodEnd Index) (lo, hi int) {
+	return findChunks(len(s.chunks), s.idxCmp, periodStart, periodEnd, func(i int) (first, last Index) {
+		chunk := s.chunks[i]
+		return s.getIdx(&chunk[0]), s.getIdx(&chunk[len(chunk)-1])
+	})
+}
+
+// Returns position of the first item with index greater than or equal to idx, or greater than idx if after is set.
+func (s *ChunkedArrayData[Data, Index]) search(chunk []Data, idx Index, after bool) int {
+	return sort.Search(len(chunk), func(i int) bool {
+		cmp := s.idxCmp(s.getIdx(&chunk[i]), idx)
+		return cmp > 0 || (cmp == 0 && !after)
+	})
+}
+
+// Replaces chunks [ lo ; hi ) with chunks, in which data of the period is replaced with provided data.
+// Not full neighbouring chunks are rewritten too, so that modifying data in small portions does not produce small chunks:
+// all rewritten chunks except the last one are full, and each not full chunk is surrounded by full ones.
+func (s *ChunkedArrayData[Data, Index]) rewrite(lo, hi int, periodStart, periodEnd Index, data []Data) {
+	var before, after []Data
+	if lo < hi {
+		before, _ = splitByPeriod(s.chunks[lo], s.getIdx, s.idxCmp, periodStart, periodEnd)
+		_, after = splitByPeriod(s.chunks[hi-1], s.getIdx, s.idxCmp, periodStart, periodEnd)
+	}
+
+	if lo > 0 && len(s.chunks[lo-1]) < s.opts.ChunkSize {
+		lo--
+		before = slices.Concat(s.chunks[lo], before)
+	}
+	if hi < len(s.chunks) && len(s.chunks[hi]) < s.opts.ChunkSize {
+		after = slices.Concat(after, s.chunks[hi])
+		hi++
+	}
+
+	chunks := make([][]Data, 0, (len(before)+len(data)+len(after))/s.opts.ChunkSize+1)
+	for chunk := range slices.Chunk(slices.Concat(before, data, after), s.opts.ChunkSize) {
+		chunks = append(chunks, chunk[:len(chunk):len(chunk)])
+	}
+
+	s.chunks = slices.Concat(s.chunks[:lo], chunks, s.chunks[hi:])
+}
@@ -0,0 +1,152 @@
+package sparse_test
+
+import (
+	"math/rand/v2"
+	"slices"
+	"testing"
+
+	"github.com/nnikolash/go-sparse"
+	"github.com/nnikolash/go-sparse/sparsetest"
+	"github.com/stretchr/testify/require"
+)
+
+func newIntChunkedArrayData(t *testing.T, opts sparse.ChunkedArrayDataOptions, data ...int) *sparse.ChunkedArrayData[int, int] {
+	factory := sparse.NewChunkedArrayDataFactory[int, int](opts)
+	s, err := factory(func(data *int) int { return *data }, func(idx1, idx2 int) int { return idx1 - idx2 }, 0, 0, data)
+	require.NoError(t, err)
+
+	return s.(*sparse.ChunkedArrayData[int, int])
+}
+
+func TestChunkedArrayData_Conformance(t *testing.T) {
+	t.Parallel()
+
+	for name, opts := range map[string]sparse.ChunkedArrayDataOptions{
+		"default":      {},
+		"single item":  {ChunkSize: 1},
+		"small chunks": {ChunkSize: 3},
+	} {
+		t.Run(name, func(t *testing.T) {
+			sparsetest.RunSeriesDataConformance(t, sparse.NewChunkedArrayDataFactory[sparsetest.Item, int](opts), sparsetest.ItemFixture())
+		})
+	}
+}
+
+func TestChunkedArrayData_Chunks(t *testing.T) {
+	t.Parallel()
+
+	s := newIntChunkedArrayData(t, sparse.ChunkedArrayDataOptions{ChunkSize: 3}, 1, 2, 3, 4, 5, 6, 7, 8, 9)
+	require.Equal(t, [][]int{{1, 2, 3}, {4, 5, 6}, {7, 8, 9}}, slices.Collect(s.Chunks(0, 10)))
+	require.Equal(t, [][]int{{3}, {4, 5, 6}, {7}}, slices.Collect(s.Chunks(3, 7)))
+
+	before := slices.Collect(s.Chunks(0, 10))
+
+	// Only the middle chunk is rewritten
+	require.NoError(t, s.Merge([]int{5}))
+	after := slices.Collect(s.Chunks(0, 10))
+	require.Equal(t, [][]int{{1, 2, 3}, {4, 5, 6}, {7, 8, 9}}, after)
+	require.Same(t, &before[0][0], &after[0][0])
+	require.NotSame(t, &before[1][0], &after[1][0])
+	require.Same(t, &before[2][0], &after[2][0])
+
+	// Small appends fill the last chunk
+	s = newIntChunkedArrayData(t, sparse.ChunkedArrayDataOptions{ChunkSize: 3})
+	for i := 1; i <= 7; i++ {
+		require.NoError(t, s.Merge([]int{i}))
+	}
+	require.Equal(t, [][]int{{1, 2, 3}, {4, 5, 6}, {7}}, slices.Collect(s.Chunks(0, 10)))
+}
+
+func TestChunkedArrayData_SmallMergesDoNotFragmentChunks(t *testing.T) {
+	t.Parallel()
+
+	const chunkSize = 8
+
+	var data []int
+	for i := 0; i < 400; i++ {
+		data = append(data, i*3)
+	}
+
+	s := newIntChunkedArrayData(t, sparse.ChunkedArrayDataOptions{ChunkSize: chunkSize}, data...)
+
+	for _, i := range rand.Perm(400)[:200] {
+		require.NoError(t, s.Merge([]int{i*3 + 1}))
+	}
+
+	chunks := slices.Collect(s.Chunks(0, 1200))
+	require.Len(t, slices.Concat(chunks...), 600)
+	require.LessOrEqual(t, len(chunks), 2*600/chunkSize+1)
+
+	for _, i := range rand.Perm(400)[:300] {
+		require.NoError(t, s.Delete(i*3, i*3+1))
+	}
+
+	chunks = slices.Collect(s.Chunks(0, 1200))
+	count := len(slices.Concat(chunks...))
+	require.LessOrEqual(t, len(chunks), 2*count/chunkSize+1)
+}
+
+func TestChunkedArrayData_Duplicates(t *testing.T) {
+	t.Parallel()
+
+	s := newIntChunkedArrayData(t, sparse.ChunkedArrayDataOptions{ChunkSize: 2}, 1, 2, 2, 2, 2, 3)
+	require.Equal(t, []int{2, 2, 2, 2}, must2(s.Get(2, 2)))
+	require.Equal(t, []int{1}, must2(s.GetEndOpen(1, 2)))
+
+	require.NoError(t, s.Merge([]int{2, 3}))
+	require.Equal(t, []int{1, 2, 3}, must2(s.Get(0, 10)))
+}
+
+func TestChunkedArrayData_MatchesArrayData(t *testing.T) {
+	t.Parallel()
+
+	getIdx := func(data *int) int { return *data }
+	idxCmp := func(idx1, idx2 int) int { return idx1 - idx2 }
+
+	for _, opts := range []sparse.ChunkedArrayDataOptions{{ChunkSize: 1}, {ChunkSize: 4}, {}} {
+		chunked := newIntChunkedArrayData(t, opts)
+		arrayData := must2(sparse.NewArrayData(getIdx, idxCmp, 0, 0, nil))
+
+		for i := 0; i < 500; i++ {
+			start := rand.IntN(100)
+			end := start + rand.IntN(20)
+
+			if rand.IntN(3) == 0 {
+				require.NoError(t, chunked.Delete(start, end))
+				require.NoError(t, arrayData.Delete(start, end))
+			} else {
+				var data []int
+				for idx := start; idx <= end; idx++ {
+					if idx == start || idx == end || rand.IntN(2) == 0 {
+						data = append(data, idx)
+					}
+				}
+
+				require.NoError(t, chunked.Merge(data))
+				require.NoError(t, arrayData.Merge(data))
+			}
+
+			require.Equal(t, must2(arrayData.Get(0, 200)), must2(chunked.Get(0, 200)), "step %v", i)
+			require.Equal(t, must2(arrayData.GetEndOpen(30, 60)), must2(chunked.GetEndOpen(30, 60)), "step %v", i)
+		}
+	}
+}
+
+func TestChunkedArrayData_Series(t *testing.T) {
+	t.Parallel()
+
+	series := sparse.NewSeries(
+		sparse.NewChunkedArrayDataFactory[int, int](sparse.ChunkedArrayDataOptions{ChunkSize: 2}),
+		func(data *int) int { return *data },
+		func(idx1, idx2 int) int { return idx1 - idx2 },
+		nil,
+	)
+	series.SetValidateOnChange(true)
+
+	require.NoError(t, series.AddPeriod(10, 20, []int{10, 15, 20}))
+	require.NoError(t, series.AddPeriod(30, 40, []int{35}))
+	require.NoError(t, series.AddPeriod(15, 35, []int{18, 25}))
+	require.NoError(t, series.DeletePeriod(0, 12))
+
+	require.Equal(t, []int{18, 25}, must2(series.Get(18, 40)))
+}
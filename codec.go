@@ -0,0 +1,81 @@
+package sparse
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+
+	"github.com/pkg/errors"
+)
+
+// Converts indexes and data of series into bytes and back. Used for persistence of series.
+type Codec[Data any, Index any] interface {
+	EncodeIndex(idx Index) ([]byte, error)
+	DecodeIndex(b []byte) (Index, error)
+	EncodeData(data []Data) ([]byte, error)
+	DecodeData(b []byte) ([]Data, error)
+}
+
+// Codec, which uses encoding/json. Used by default, so binary formats are just a wrapper around JSON,
+// unless other codec is set (e.g. GobCodec or custom one).
+type JSONCodec[Data any, Index any] struct{}
+
+var _ Codec[int, int] = JSONCodec[int, int]{}
+
+func (JSONCodec[Data, Index]) EncodeIndex(idx Index) ([]byte, error) {
+	b, err := json.Marshal(idx)
+	return b, errors.Wrap(err, "failed to encode index")
+}
+
+func (JSONCodec[Data, Index]) DecodeIndex(b []byte) (Index, error) {
+	var idx Index
+	err := json.Unmarshal(b, &idx)
+	return idx, errors.Wrap(err, "failed to decode index")
+}
+
+func (JSONCodec[Data, Index]) EncodeData(data []Data) ([]byte, error) {
+	b, err := json.Marshal(data)
+	return b, errors.Wrap(err, "failed to encode data")
+}
+
+func (JSONCodec[Data, Index]) DecodeData(b []byte) ([]Data, error) {
+	var data []Data
+	err := json.Unmarshal(b, &data)
+	return data, errors.Wrap(err, "failed to decode data")
+}
+
+// Codec, which uses encoding/gob. Data and indexes must be supported by encoding/gob.
+type GobCodec[Data any, Index any] struct{}
+
+var _ Codec[int, int] = GobCodec[int, int]{}
+
+func (GobCodec[Data, Index]) EncodeIndex(idx Index) ([]byte, error) {
+	b, err := gobEncode(idx)
+	return b, errors.Wrap(err, "failed to encode index")
+}
+
+func (GobCodec[Data, Index]) DecodeIndex(b []byte) (Index, error) {
+	var idx Index
+	err := gob.NewDecoder(bytes.NewReader(b)).Decode(&idx)
+	return idx, errors.Wrap(err, "failed to decode index")
+}
+
+func (GobCodec[Data, Index]) EncodeData(data []Data) ([]byte, error) {
+	b, err := gobEncode(data)
+	return b, errors.Wrap(err, "failed to encode data")
+}
+
+func (GobCodec[Data, Index]) DecodeData(b []byte) ([]Data, error) {
+	var data []Data
+	err := gob.NewDecoder(bytes.NewReader(b)).Decode(&data)
+	return data, errors.Wrap(err, "failed to decode data")
+}
+
+func gobEncode(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(v); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
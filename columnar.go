@@ -0,0 +1,311 @@
+package sparse
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+// Optionally implemented by storages, which are able to return values of single field without building whole items.
+type ColumnarSeriesData[Index any] interface {
+	// Returns values of the column ([]T for column of type T) of items within the closed period [ periodStart ; periodEnd ].
+	// Returned slice must not be modified.
+	Column(name string, periodStart, periodEnd Index) (any, error)
+}
+
+// Returns values of the column of type T within the period. Reader may be Series, its snapshot or columnar storage.
+func GetColumn[T any, Index any](reader ColumnarSeriesData[Index], name string, periodStart, periodEnd Index) ([]T, error) {
+	values, err := reader.Column(name, periodStart, periodEnd)
+	if err != nil {
+		return nil, err
+	}
+
+	typed, ok := values.([]T)
+	if !ok {
+		return nil, errors.Errorf("column %v has type %T, but %T was requested", name, values, []T{})
+	}
+
+	return typed, nil
+}
+
+// Field of the data, which is stored in separate column.
+type Column[Data any] interface {
+	Name() string
+	newValues() columnValues[Data]
+}
+
+// Creates column of type T, values of which are extracted from the data using get and put back using set.
+func NewColumn[Data any, T any](name string, get func(data *Data) T, set func(data *Data, v T)) Column[Data] {
+	return &typedColumn[Data, T]{name: name, get: get, set: set}
+}
+
+type typedColumn[Data any, T any] struct {
+	name string
+	get  func(data *Data) T
+	set  func(data *Data, v T)
+}
+
+func (c *typedColumn[Data, T]) Name() string {
+	return c.name
+}
+
+func (c *typedColumn[Data, T]) newValues() columnValues[Data] {
+	return &typedColumnValues[Data, T]{column: c}
+}
+
+// Values of single column. Values are never modified in place.
+type columnValues[Data any] interface {
+	// Returns new values, in which range [ lo ; hi ) is replaced with values of the data.
+	replace(lo, hi int, data []Data) columnValues[Data]
+	// Sets values starting from position lo into the data.
+	fill(lo int, data []Data)
+	// Returns values in range [ lo ; hi ).
+	slice(lo, hi int) any
+}
+
+type typedColumnValues[Data any, T any] struct {
+	column *typedColumn[Data, T]
+	values []T
+}
+
+func (v *typedColumnValues[Data, T]) replace(lo, hi int, data []Data) columnValues[Data] {
+	values := make([]T, 0, len(v.values)-(hi-lo)+len(data))
+	values = append(values, v.values[:lo]...)
+	for i := range data {
+		values = append(values, v.column.get(&data[i]))
+	}
+	values = append(values, v.values[hi:]...)
+
+	return &typedColumnValues[Data, T]{column: v.column, values: values}
+}
+
+func (v *typedColumnValues[Data, T]) fill(lo int, data []Data) {
+	for i := range data {
+		v.column.set(&data[i], v.values[lo+i])
+	}
+}
+
+func (v *typedColumnValues[Data, T]) slice(lo, hi int) any {
+	return v.values[lo:hi:hi]
+}
+
+type ColumnarDataSchema[Data any, Index any] struct {
+	// Sets index of the item, which is built from columns.
+	SetIdx func(data *Data, idx Index)
+	// Fields of the data, which are not stored in any column, are lost.
+	Columns []Column[Data]
+}
+
+// Creates factory of ColumnarData. Schema must have SetIdx and at least one column, and names of columns must be unique.
+// Invalid schema is reported by each call of the factory.
+func NewColumnarDataFactory[Data any, Index any](schema ColumnarDataSchema[Data, Index]) SeriesDataFactory[Data, Index] {
+	schemaErr := schema.validate()
+
+	return func(
+		getIdx func(data *Data) Index,
+		idxCmp func(idx1, idx2 Index) int,
+		periodStart, periodEnd Index, data []Data,
+	) (SeriesData[Data, Index], error) {
+		if schemaErr != nil {
+			return nil, schemaErr
+		}
+
+		s := &ColumnarData[Data, Index]{
+			getIdx:  getIdx,
+			idxCmp:  idxCmp,
+			schema:  schema,
+			columns: make([]columnValues[Data], 0, len(schema.Columns)),
+		}
+
+		for _, column := range schema.Columns {
+			s.columns = append(s.columns, column.newValues())
+		}
+
+		if err := s.Merge(data); err != nil {
+			return nil, err
+		}
+
+		return s, nil
+	}
+}
+
+var _ SeriesDataFactory[int, int] = NewColumnarDataFactory[int, int](ColumnarDataSchema[int, int]{})
+
+func (s *ColumnarDataSchema[Data, Index]) validate() error {
+	if s.SetIdx == nil {
+		return errors.New("columnar schema has no index setter")
+	}
+	if len(s.Columns) == 0 {
+		return errors.New("columnar schema has no columns")
+	}
+
+	names := make(map[string]struct{}, len(s.Columns))
+
+	for i, column := range s.Columns {
+		if column == nil {
+			return errors.Errorf("column %v of columnar schema is nil", i)
+		}
+
+		if _, ok := names[column.Name()]; ok {
+			return errors.Errorf("columnar schema has multiple columns named %v", column.Name())
+		}
+		names[column.Name()] = struct{}{}
+	}
+
+	return nil
+}
+
+// Storage in memory, which keeps index and each field of the data in separate typed slices.
+// Items are built from columns on each read, while Column returns values of single field without copying.
+type ColumnarData[Data any, Index any] struct {
+	getIdx func(data *Data) Index
+	idxCmp func(idx1, idx2 Index) int
+	schema ColumnarDataSchema[Data, Index]
+
+	// Index and columns are never modified in place
+	idxs    []Index
+	columns []columnValues[Data]
+}
+
+func (s *ColumnarData[Data, Index]) First(idx Index) (*Data, error) {
+	if len(s.idxs) == 0 {
+		return nil, nil
+	}
+
+	return &s.rows(0, 1)[0], nil
+}
+
+func (s *ColumnarData[Data, Index]) Last(idx Index) (*Data, error) {
+	if len(s.idxs) == 0 {
+		return nil, nil
+	}
+
+	return &s.rows(len(s.idxs)-1, len(s.idxs))[0], nil
+}
+
+func (s *ColumnarData[Data, Index]) Get(periodStart, periodEnd Index) ([]Data, error) {
+	lo, hi := s.find(periodStart, periodEnd, false)
+	return s.rows(lo, hi), nil
+}
+
+func (s *ColumnarData[Data, Index]) GetEndOpen(periodStart, periodEnd Index) ([]Data, error) {
+	lo, hi := s.find(periodStart, periodEnd, true)
+	return s.rows(lo, hi), nil
+}
+
+func (s *ColumnarData[Data, Index]) Column(name string, periodStart, periodEnd Index) (any, error) {
+	for i, column := range s.schema.Columns {
+		if column.Name() == name {
+			lo, hi := s.find(periodStart, periodEnd, false)
+			return s.columns[i].slice(lo, hi), nil
+		}
+	}
+
+	return nil, errors.Errorf("unknown column %v", name)
+}
+
+var _ ColumnarSeriesData[int] = &ColumnarData[int, int]{}
+
+// Returns index values within the period. Returned slice must not be modified.
+func (s *ColumnarData[Data, Index]) Indexes(periodStart, periodEnd Index) []Index {
+	lo, hi := s.find(periodStart, periodEnd, false)
+	return s.idxs[lo:hi:hi]
+}
+
+func (s *ColumnarData[Data, Index]) Merge(data []Data) error {
+	if len(data) == 0 {
+		return nil
+	}
+
+	lo, hi := s.find(s.getIdx(&data[0]), s.getIdx(&data[len(data)-1]), false)
+	s.replace(lo, hi, data)
+
+	return nil
+}
+
+func (s *ColumnarData[Data, Index]) Delete(periodStart, periodEnd Index) error {
+	lo, hi := s.find(periodStart, periodEnd, false)
+	if lo < hi {
+		s.replace(lo, hi, nil)
+	}
+
+	return nil
+}
+
+// Columns are never modified in place, so snapshot just shares them.
+func (s *ColumnarData[Data, Index]) Snapshot() (SeriesData[Data, Index], error) {
+	snapshot := *s
+	return &snapshot, nil
+}
+
+var _ SnapshotableSeriesData[int, int] = &ColumnarData[int, int]{}
+
+func (s *ColumnarData[Data, Index]) String() string {
+	return fmt.Sprintf("%v", s.rows(0, len(s.idxs)))
+}
+
+// Returns range [ lo ; hi ) of items within the period.
+func (s *ColumnarData[Data, Index]) find(periodStart, periodEnd Index, endOpen bool) (lo, hi int) {
+	lo = sort.Search(len(s.idxs), func(i int) bool {
+		return s.idxCmp(s.idxs[i], periodStart) >= 0
+	})
+
+	hi = lo + sort.Search(len(s.idxs)-lo, func(i int) bool {
+		cmp := s.idxCmp(s.idxs[lo+i], periodEnd)
+		return cmp > 0 || (endOpen && cmp == 0)
+	})
+
+	return lo, hi
+}
+
+func (s *ColumnarData[Data, Index]) replace(lo, hi int, data []Data) {
+	idxs := make([]Index, 0, len(s.idxs)-(hi-lo)+len(data))
+	idxs = append(idxs, s.idxs[:lo]...)
+	for i := range data {
+		idxs = append(idxs, s.getIdx(&data[i]))
+	}
+	s.idxs = append(idxs, s.idxs[hi:]...)
+
+	columns := make([]columnValues[Data], 0, len(s.columns))
+	for _, column := range s.columns {
+		columns = append(columns, column.replace(lo, hi, data))
+	}
+	s.columns = columns
+}
+
+func (s *ColumnarData[Data, Index]) rows(lo, hi int) []Data {
+	rows := make([]Data, hi-lo)
+
+	for i := range rows {
+		s.schema.SetIdx(&rows[i], s.idxs[lo+i])
+	}
+
+	for _, column := range s.columns {
+		column.fill(lo, rows)
+	}
+
+	return rows
+}
+
+// Returns values of the column within the period of storage, which implements ColumnarSeriesData.
+// Period must be fully covered by the series.
+func (s *Series[Data, Index]) Column(name string, periodStart, periodEnd Index) (any, error) {
+	segment, err := s.getCoveringSegment(periodStart, periodEnd)
+	if err != nil {
+		return nil, err
+	}
+
+	columnar, ok := segment.Data.(ColumnarSeriesData[Index])
+	if !ok {
+		return nil, errors.Errorf("storage %T does not support columns", segment.Data)
+	}
+
+	return columnar.Column(name, periodStart, periodEnd)
+}
+
+var _ ColumnarSeriesData[int] = &Series[int, int]{}
+
+func (s *SeriesSnapshot[Data, Index]) Column(name string, periodStart, periodEnd Index) (any, error) {
+	return s.series.Column(name, periodStart, periodEnd)
+}
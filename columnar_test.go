@@ -0,0 +1,159 @@
+package sparse_test
+
+import (
+	"testing"
+
+	"github.com/nnikolash/go-sparse"
+	"github.com/nnikolash/go-sparse/sparsetest"
+	"github.com/stretchr/testify/require"
+)
+
+type trade struct {
+	Time   int
+	Price  float64
+	Volume int64
+	Side   string
+}
+
+var tradeSchema = sparse.ColumnarDataSchema[trade, int]{
+	SetIdx: func(t *trade, idx int) { t.Time = idx },
+	Columns: []sparse.Column[trade]{
+		sparse.NewColumn("price", func(t *trade) float64 { return t.Price }, func(t *trade, v float64) { t.Price = v }),
+		sparse.NewColumn("volume", func(t *trade) int64 { return t.Volume }, func(t *trade, v int64) { t.Volume = v }),
+		sparse.NewColumn("side", func(t *trade) string { return t.Side }, func(t *trade, v string) { t.Side = v }),
+	},
+}
+
+func tradeSeries() *sparse.Series[trade, int] {
+	return sparse.NewSeries(
+		sparse.NewColumnarDataFactory(tradeSchema),
+		func(t *trade) int { return t.Time },
+		func(idx1, idx2 int) int { return idx1 - idx2 },
+		nil,
+	)
+}
+
+func TestColumnarData_Conformance(t *testing.T) {
+	t.Parallel()
+
+	factory := sparse.NewColumnarDataFactory(sparse.ColumnarDataSchema[sparsetest.Item, int]{
+		SetIdx: func(item *sparsetest.Item, idx int) { item.Idx = idx },
+		Columns: []sparse.Column[sparsetest.Item]{
+			sparse.NewColumn("val", func(item *sparsetest.Item) float64 { return item.Val }, func(item *sparsetest.Item, v float64) { item.Val = v }),
+		},
+	})
+
+	sparsetest.RunSeriesDataConformance(t, factory, sparsetest.ItemFixture())
+}
+
+func TestColumnarData_InvalidSchema(t *testing.T) {
+	t.Parallel()
+
+	price := sparse.NewColumn("price", func(t *trade) float64 { return t.Price }, func(t *trade, v float64) { t.Price = v })
+	setIdx := func(t *trade, idx int) { t.Time = idx }
+
+	schemas := map[string]sparse.ColumnarDataSchema[trade, int]{
+		"no index setter":   {Columns: []sparse.Column[trade]{price}},
+		"no columns":        {SetIdx: setIdx},
+		"nil column":        {SetIdx: setIdx, Columns: []sparse.Column[trade]{price, nil}},
+		"duplicate columns": {SetIdx: setIdx, Columns: []sparse.Column[trade]{price, sparse.NewColumn("price", func(t *trade) int64 { return t.Volume }, func(t *trade, v int64) { t.Volume = v })}},
+	}
+
+	for name, schema := range schemas {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			factory := sparse.NewColumnarDataFactory(schema)
+
+			_, err := factory(func(t *trade) int { return t.Time }, func(idx1, idx2 int) int { return idx1 - idx2 }, 0, 0, nil)
+			require.Error(t, err)
+
+			series := sparse.NewSeries(factory, func(t *trade) int { return t.Time }, func(idx1, idx2 int) int { return idx1 - idx2 }, nil)
+			require.Error(t, series.AddPeriod(0, 10, []trade{{Time: 5}}))
+			require.Empty(t, series.Segments())
+		})
+	}
+}
+
+func TestColumnarData_Column(t *testing.T) {
+	t.Parallel()
+
+	series := tradeSeries()
+	series.SetValidateOnChange(true)
+
+	trades := []trade{
+		{Time: 1, Price: 10, Volume: 100, Side: "buy"},
+		{Time: 2, Price: 11, Volume: 200, Side: "sell"},
+		{Time: 4, Price: 12, Volume: 300, Side: "buy"},
+	}
+	require.NoError(t, series.AddPeriod(0, 5, trades))
+	require.NoError(t, series.AddPeriod(3, 5, []trade{{Time: 5, Price: 13, Volume: 400, Side: "sell"}}))
+
+	res, err := series.Get(0, 5)
+	require.NoError(t, err)
+	require.Equal(t, []trade{trades[0], trades[1], {Time: 5, Price: 13, Volume: 400, Side: "sell"}}, res)
+
+	prices, err := sparse.GetColumn[float64](series, "price", 2, 5)
+	require.NoError(t, err)
+	require.Equal(t, []float64{11, 13}, prices)
+
+	volumes, err := sparse.GetColumn[int64](series, "volume", 0, 1)
+	require.NoError(t, err)
+	require.Equal(t, []int64{100}, volumes)
+
+	sides, err := sparse.GetColumn[string](series, "side", 0, 5)
+	require.NoError(t, err)
+	require.Equal(t, []string{"buy", "sell", "sell"}, sides)
+
+	segment := series.GetSegment(0)
+	columnar := segment.Data.(*sparse.ColumnarData[trade, int])
+	require.Equal(t, []int{1, 2, 5}, columnar.Indexes(0, 5))
+
+	// Values returned before modification are not affected by it
+	require.NoError(t, series.AddPeriod(2, 2, nil))
+	require.Equal(t, []float64{11, 13}, prices)
+
+	prices, err = sparse.GetColumn[float64](series, "price", 0, 5)
+	require.NoError(t, err)
+	require.Equal(t, []float64{10, 13}, prices)
+}
+
+func TestColumnarData_ColumnErrors(t *testing.T) {
+	t.Parallel()
+
+	series := tradeSeries()
+	require.NoError(t, series.AddPeriod(0, 5, []trade{{Time: 1, Price: 10}}))
+
+	_, err := sparse.GetColumn[float64](series, "unknown", 0, 5)
+	require.Error(t, err)
+
+	_, err = sparse.GetColumn[int](series, "price", 0, 5)
+	require.Error(t, err)
+
+	_, err = sparse.GetColumn[float64](series, "price", 0, 10)
+	var missingErr *sparse.MissingPeriodError[int]
+	require.ErrorAs(t, err, &missingErr)
+
+	_, err = sparse.GetColumn[int](intSparseSeries(), "value", 0, 10)
+	require.Error(t, err)
+}
+
+func TestColumnarData_Snapshot(t *testing.T) {
+	t.Parallel()
+
+	series := tradeSeries()
+	require.NoError(t, series.AddPeriod(0, 5, []trade{{Time: 1, Price: 10}, {Time: 3, Price: 20}}))
+
+	snapshot, err := series.Snapshot()
+	require.NoError(t, err)
+
+	require.NoError(t, series.AddPeriod(2, 4, []trade{{Time: 2, Price: 30}}))
+
+	prices, err := sparse.GetColumn[float64](snapshot, "price", 0, 5)
+	require.NoError(t, err)
+	require.Equal(t, []float64{10, 20}, prices)
+
+	prices, err = sparse.GetColumn[float64](series, "price", 0, 5)
+	require.NoError(t, err)
+	require.Equal(t, []float64{10, 30}, prices)
+}
@@ -0,0 +1,257 @@
+package sparse
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/pkg/errors"
+)
+
+const defaultCompressedDataBlockSize = 512
+
+// Converts data item into pair of timestamp and value, which are stored by CompressedData, and back.
+// Index of decoded item must be the same as of the original one.
+type CompressedDataCodec[Data any] struct {
+	Encode func(data *Data) (timestamp int64, value float64)
+	Decode func(timestamp int64, value float64) Data
+}
+
+type CompressedDataOptions struct {
+	// Maximum number of items in single block. Zero means default value.
+	BlockSize int
+}
+
+// Creates factory of storages, which keep data compressed in the style of Gorilla.
+func NewCompressedDataFactory[Data any, Index any](codec CompressedDataCodec[Data], opts CompressedDataOptions) SeriesDataFactory[Data, Index] {
+	if opts.BlockSize <= 0 {
+		opts.BlockSize = defaultCompressedDataBlockSize
+	}
+
+	return func(
+		getIdx func(data *Data) Index,
+		idxCmp func(idx1, idx2 Index) int,
+		periodStart, periodEnd Index, data []Data,
+	) (SeriesData[Data, Index], error) {
+		s := &CompressedData[Data, Index]{
+			getIdx: getIdx,
+			idxCmp: idxCmp,
+			codec:  codec,
+			opts:   opts,
+		}
+
+		if err := s.Merge(data); err != nil {
+			return nil, err
+		}
+
+		return s, nil
+	}
+}
+
+var _ SeriesDataFactory[int, int] = NewCompressedDataFactory[int, int](CompressedDataCodec[int]{}, CompressedDataOptions{})
+
+// Storage for time-indexed numeric data. Data is split into blocks of fixed size, in which timestamps
+// are stored as delta-of-delta and values are XOR-encoded. Only blocks, which intersect requested period,
+// are decoded, and only blocks, which intersect merged or deleted period, are re-encoded.
+type CompressedData[Data any, Index any] struct {
+	getIdx func(data *Data) Index
+	idxCmp func(idx1, idx2 Index) int
+	codec  CompressedDataCodec[Data]
+	opts   CompressedDataOptions
+
+	blocks []compressedBlock[Index]
+}
+
+type compressedBlock[Index any] struct {
+	first, last Index
+	count       int
+	b           []byte
+}
+
+func (s *CompressedData[Data, Index]) First(idx Index) (*Data, error) {
+	if len(s.blocks) == 0 {
+		return nil, nil
+	}
+
+	data, err := s.decodeBlock(s.blocks[0], 1)
+	if err != nil {
+		return nil, err
+	}
+
+	return &data[0], nil
+}
+
+func (s *CompressedData[Data, Index]) Last(idx Index) (*Data, error) {
+	if len(s.blocks) == 0 {
+		return nil, nil
+	}
+
+	data, err := s.decodeBlock(s.blocks[len(s.blocks)-1], -1)
+	if err != nil {
+		return nil, err
+	}
+
+	return &data[len(data)-1], nil
+}
+
+func (s *CompressedData[Data, Index]) Get(periodStart, periodEnd Index) ([]Data, error) {
+	return s.get(periodStart, periodEnd, false)
+}
+
+func (s *CompressedData[Data, Index]) GetEndOpen(periodStart, periodEnd Index) ([]Data, error) {
+	return s.get(periodStart, periodEnd, true)
+}
+
+func (s *CompressedData[Data, Index]) get(periodStart, periodEnd Index, endOpen bool) ([]Data, error) {
+	res := []Data{}
+
+	lo, hi := s.findBlocks(periodStart, periodEnd)
+
+	for _, block := range s.blocks[lo:hi] {
+		data, err := s.decodeBlock(block, -1)
+		if err != nil {
+			return nil, err
+		}
+
+		for i := range data {
+			idx := s.getIdx(&data[i])
+			if s.idxCmp(idx, periodStart) < 0 {
+				continue
+			}
+			if s.idxCmp(idx, periodEnd) > 0 || (endOpen && s.idxCmp(idx, periodEnd) == 0) {
+				break
+			}
+
+			res = append(res, data[i])
+		}
+	}
+
+	return res, nil
+}
+
+func (s *CompressedData[Data, Index]) Merge(data []Data) error {
+	if len(data) == 0 {
+		return nil
+	}
+
+	periodStart := s.getIdx(&data[0])
+	periodEnd := s.getIdx(&data[len(data)-1])
+
+	lo, hi := s.findBlocks(periodStart, periodEnd)
+
+	// Appending to not full block instead of creating new one, so that adding data in small portions does not produce small blocks
+	if lo == hi && lo > 0 && s.blocks[lo-1].count < s.opts.BlockSize {
+		lo--
+	}
+
+	return s.reencode(lo, hi, func(old []Data) []Data {
+		before, after := splitByPeriod(old, s.getIdx, s.idxCmp, periodStart, periodEnd)
+		return slices.Concat(before, data, after)
+	})
+}
+
+func (s *CompressedData[Data, Index]) Delete(periodStart, periodEnd Index) error {
+	lo, hi := s.findBlocks(periodStart, periodEnd)
+	if lo == hi {
+		return nil
+	}
+
+	return s.reencode(lo, hi, func(old []Data) []Data {
+		before, after := splitByPeriod(old, s.getIdx, s.idxCmp, periodStart, periodEnd)
+		return slices.Concat(before, after)
+	})
+}
+
+// Blocks are never modified in place, so snapshot just shares them.
+func (s *CompressedData[Data, Index]) Snapshot() (SeriesData[Data, Index], error) {
+	snapshot := *s
+	return &snapshot, nil
+}
+
+var _ SnapshotableSeriesData[int, int] = &CompressedData[int, int]{}
+
+// Returns size of compressed data in bytes.
+func (s *CompressedData[Data, Index]) Size() int {
+	size := 0
+	for _, block := range s.blocks {
+		size += len(block.b)
+	}
+
+	return size
+}
+
+func (s *CompressedData[Data, Index]) String() string {
+	count := 0
+	for _, block := range s.blocks {
+		count += block.count
+	}
+
+	return fmt.Sprintf("<%v items in %v blocks, %v bytes>", count, len(s.blocks), s.Size())
+}
+
+// Returns range [ lo ; hi ) of blocks, which intersect the period.
+func (s *CompressedData[Data, Index]) findBlocks(periodStart, periodEnd Index) (lo, hi int) {
+	return findChunks(len(s.blocks), s.idxCmp, periodStart, periodEnd, func(i int) (first, last Index) {
+		return s.blocks[i].first, s.blocks[i].last
+	})
+}
+
+// Replaces blocks [ lo ; hi ) with blocks, which contain result of modification of their data.
+func (s *CompressedData[Data, Index]) reencode(lo, hi int, modify func(old []Data) []Data) error {
+	var old []Data
+
+	for _, block := range s.blocks[lo:hi] {
+		data, err := s.decodeBlock(block, -1)
+		if err != nil {
+			return err
+		}
+
+		old = append(old, data...)
+	}
+
+	data := modify(old)
+
+	blocks := make([]compressedBlock[Index], 0, (len(data)+s.opts.BlockSize-1)/s.opts.BlockSize)
+	for chunk := range slices.Chunk(data, s.opts.BlockSize) {
+		blocks = append(blocks, s.encodeBlock(chunk))
+	}
+
+	s.blocks = slices.Concat(s.blocks[:lo], blocks, s.blocks[hi:])
+
+	return nil
+}
+
+func (s *CompressedData[Data, Index]) encodeBlock(data []Data) compressedBlock[Index] {
+	var e gorillaEncoder
+
+	for i := range data {
+		e.encode(s.codec.Encode(&data[i]))
+	}
+
+	return compressedBlock[Index]{
+		first: s.getIdx(&data[0]),
+		last:  s.getIdx(&data[len(data)-1]),
+		count: len(data),
+		b:     e.w.b,
+	}
+}
+
+// Decodes first limit items of the block. Negative limit means all items.
+func (s *CompressedData[Data, Index]) decodeBlock(block compressedBlock[Index], limit int) ([]Data, error) {
+	if limit < 0 || limit > block.count {
+		limit = block.count
+	}
+
+	d := gorillaDecoder{r: bitReader{b: block.b}}
+	data := make([]Data, 0, limit)
+
+	for range limit {
+		ts, value, err := d.decode()
+		if err != nil {
+			return nil, errors.WithStack(&StorageCorruptedError[Index]{PeriodStart: block.first, PeriodEnd: block.last, Err: err})
+		}
+
+		data = append(data, s.codec.Decode(ts, value))
+	}
+
+	return data, nil
+}
@@ -0,0 +1,123 @@
+package sparse_test
+
+import (
+	"math"
+	"math/rand/v2"
+	"testing"
+
+	"github.com/nnikolash/go-sparse"
+	"github.com/nnikolash/go-sparse/sparsetest"
+	"github.com/stretchr/testify/require"
+)
+
+var itemCompressedDataCodec = sparse.CompressedDataCodec[sparsetest.Item]{
+	Encode: func(item *sparsetest.Item) (int64, float64) { return int64(item.Idx), item.Val },
+	Decode: func(ts int64, value float64) sparsetest.Item { return sparsetest.Item{Idx: int(ts), Val: value} },
+}
+
+func newItemCompressedData(t *testing.T, opts sparse.CompressedDataOptions, data ...sparsetest.Item) *sparse.CompressedData[sparsetest.Item, int] {
+	factory := sparse.NewCompressedDataFactory[sparsetest.Item, int](itemCompressedDataCodec, opts)
+	return must2(factory(sparsetest.ItemIdx, sparsetest.ItemIdxCmp, 0, 0, data)).(*sparse.CompressedData[sparsetest.Item, int])
+}
+
+func TestCompressedData_Conformance(t *testing.T) {
+	t.Parallel()
+
+	for name, opts := range map[string]sparse.CompressedDataOptions{
+		"default":      {},
+		"single item":  {BlockSize: 1},
+		"small blocks": {BlockSize: 3},
+	} {
+		t.Run(name, func(t *testing.T) {
+			sparsetest.RunSeriesDataConformance(t, sparse.NewCompressedDataFactory[sparsetest.Item, int](itemCompressedDataCodec, opts), sparsetest.ItemFixture())
+		})
+	}
+}
+
+func TestCompressedData_Values(t *testing.T) {
+	t.Parallel()
+
+	values := []float64{0, math.Copysign(0, -1), 1, 1, 1.5, -1e300, math.MaxFloat64, math.SmallestNonzeroFloat64, math.Inf(1), math.Inf(-1), 42, 42.000001}
+	idxs := []int{-1 << 60, -1 << 40, -5000, -100, -99, 0, 1, 2, 3, 3000, 1 << 50, 1 << 60}
+
+	data := make([]sparsetest.Item, 0, len(idxs))
+	for i, idx := range idxs {
+		data = append(data, item(idx, values[i]))
+	}
+
+	compressed := newItemCompressedData(t, sparse.CompressedDataOptions{}, data...)
+	res := must2(compressed.Get(-1<<60, 1<<60))
+	require.Equal(t, data, res)
+	require.True(t, math.Signbit(res[1].Val))
+
+	nan := newItemCompressedData(t, sparse.CompressedDataOptions{}, item(1, math.NaN()))
+	require.True(t, math.IsNaN(must2(nan.First(0)).Val))
+}
+
+func TestCompressedData_Size(t *testing.T) {
+	t.Parallel()
+
+	const minute = 60
+	data := make([]sparsetest.Item, 0, 60*24*365)
+	value := 100.0
+
+	for i := range cap(data) {
+		if i%10 == 0 {
+			value += 0.25
+		}
+		data = append(data, item(1700000000+i*minute, value))
+	}
+
+	compressed := newItemCompressedData(t, sparse.CompressedDataOptions{}, data...)
+	require.Less(t, compressed.Size(), len(data)*16/8)
+
+	res, err := compressed.Get(1700000000+10*minute, 1700000000+20*minute)
+	require.NoError(t, err)
+	require.Equal(t, data[10:21], res)
+}
+
+func TestCompressedData_Series(t *testing.T) {
+	t.Parallel()
+
+	series := itemSparseSeries(sparse.NewCompressedDataFactory[sparsetest.Item, int](itemCompressedDataCodec, sparse.CompressedDataOptions{BlockSize: 2}))
+	series.SetValidateOnChange(true)
+
+	require.NoError(t, series.AddPeriod(10, 20, sparsetest.Items(1, 10, 15, 20)))
+	require.NoError(t, series.AddPeriod(30, 40, sparsetest.Items(1, 35)))
+	require.NoError(t, series.AddPeriod(15, 35, sparsetest.Items(2, 18, 25)))
+	require.NoError(t, series.DeletePeriod(0, 12))
+
+	require.Equal(t, []sparsetest.Item{item(18, 36), item(25, 50)}, must2(series.Get(18, 40)))
+}
+
+func TestCompressedData_MatchesArrayData(t *testing.T) {
+	t.Parallel()
+
+	for _, opts := range []sparse.CompressedDataOptions{{BlockSize: 1}, {BlockSize: 4}, {}} {
+		compressed := newItemCompressedData(t, opts)
+		arrayData := must2(sparse.NewArrayData(sparsetest.ItemIdx, sparsetest.ItemIdxCmp, 0, 0, nil))
+
+		for i := 0; i < 500; i++ {
+			start := rand.IntN(100)
+			end := start + rand.IntN(20)
+
+			if rand.IntN(3) == 0 {
+				require.NoError(t, compressed.Delete(start, end))
+				require.NoError(t, arrayData.Delete(start, end))
+			} else {
+				var data []sparsetest.Item
+				for idx := start; idx <= end; idx++ {
+					if idx == start || idx == end || rand.IntN(2) == 0 {
+						data = append(data, item(idx, rand.NormFloat64()))
+					}
+				}
+
+				require.NoError(t, compressed.Merge(data))
+				require.NoError(t, arrayData.Merge(data))
+			}
+
+			require.Equal(t, must2(arrayData.Get(0, 200)), must2(compressed.Get(0, 200)), "step %v", i)
+			require.Equal(t, must2(arrayData.GetEndOpen(30, 60)), must2(compressed.GetEndOpen(30, 60)), "step %v", i)
+		}
+	}
+}
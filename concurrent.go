@@ -0,0 +1,274 @@
+package sparse
+
+import (
+	"iter"
+	"slices"
+	"sync"
+	"sync/atomic"
+)
+
+// Thread-safe version of Series.
+// Returned segments are shared with the series, so their data must not be accessed concurrently with writes.
+func NewConcurrentSeries[Data any, Index any](
+	storageFactory SeriesDataFactory[Data, Index],
+	getIdx func(data *Data) Index,
+	cmp func(idx1, idx2 Index) int,
+	areContinuous func(smaller, bigger Index) bool,
+) *ConcurrentSeries[Data, Index] {
+	return &ConcurrentSeries[Data, Index]{
+		series: NewSeries(storageFactory, getIdx, cmp, areContinuous),
+	}
+}
+
+type ConcurrentSeries[Data any, Index any] struct {
+	mtx    sync.RWMutex
+	series *Series[Data, Index]
+	tiered atomic.Bool
+}
+
+// Reads of tiered series move data between storages, so they require exclusive lock.
+func (s *ConcurrentSeries[Data, Index]) lockForRead() (unlock func()) {
+	s.mtx.RLock()
+	if !s.tiered.Load() {
+		return s.mtx.RUnlock
+	}
+	s.mtx.RUnlock()
+
+	s.mtx.Lock()
+	return s.mtx.Unlock
+}
+
+func (s *ConcurrentSeries[Data, Index]) Segments() []*SeriesSegment[Data, Index] {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+
+	return s.series.Segments()
+}
+
+func (s *ConcurrentSeries[Data, Index]) SegmentsString() string {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+
+	return s.series.SegmentsString()
+}
+
+func (s *ConcurrentSeries[Data, Index]) GetAllSegments() []*SeriesSegment[Data, Index] {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+
+	return s.series.GetAllSegments()
+}
+
+func (s *ConcurrentSeries[Data, Index]) GetSegment(t Index) *SeriesSegment[Data, Index] {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+
+	return s.series.GetSegment(t)
+}
+
+func (s *ConcurrentSeries[Data, Index]) Get(periodStart, periodEnd Index) ([]Data, error) {
+	defer s.lockForRead()()
+
+	return s.series.Get(periodStart, periodEnd)
+}
+
+// Sequence is created under read lock, but iterated without it.
+// It is safe for storages, which capture the data on creation of sequence (e.g. ArrayData).
+func (s *ConcurrentSeries[Data, Index]) All(periodStart, periodEnd Index) (iter.Seq2[Index, Data], error) {
+	defer s.lockForRead()()
+
+	return s.series.All(periodStart, periodEnd)
+}
+
+func (s *ConcurrentSeries[Data, Index]) Backward(periodStart, periodEnd Index) (iter.Seq2[Index, Data], error) {
+	defer s.lockForRead()()
+
+	return s.series.Backward(periodStart, periodEnd)
+}
+
+// Segments are collected under read lock, so series can be modified during iteration.
+func (s *ConcurrentSeries[Data, Index]) SegmentsBetween(periodStart, periodEnd Index, nonEmptyOnly bool) iter.Seq[*SeriesSegment[Data, Index]] {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+
+	segments := slices.Collect(s.series.SegmentsBetween(periodStart, periodEnd, nonEmptyOnly))
+
+	return slices.Values(segments)
+}
+
+func (s *ConcurrentSeries[Data, Index]) GetAvailable(periodStart, periodEnd Index) (available []PeriodData[Data, Index], missing []PeriodBounds[Index], _ error) {
+	defer s.lockForRead()()
+
+	return s.series.GetAvailable(periodStart, periodEnd)
+}
+
+func (s *ConcurrentSeries[Data, Index]) GetPeriod(periodStart, periodEnd Index) *SeriesSegment[Data, Index] {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+
+	return s.series.GetPeriod(periodStart, periodEnd)
+}
+
+func (s *ConcurrentSeries[Data, Index]) GetPeriodClosestFromStart(t Index, nonEmpty bool) *SeriesSegment[Data, Index] {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+
+	return s.series.GetPeriodClosestFromStart(t, nonEmpty)
+}
+
+func (s *ConcurrentSeries[Data, Index]) GetPeriodClosestFromEnd(t Index, nonEmpty bool) *SeriesSegment[Data, Index] {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+
+	return s.series.GetPeriodClosestFromEnd(t, nonEmpty)
+}
+
+func (s *ConcurrentSeries[Data, Index]) MissingPeriods(periodStart, periodEnd Index) []PeriodBounds[Index] {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+
+	return s.series.MissingPeriods(periodStart, periodEnd)
+}
+
+func (s *ConcurrentSeries[Data, Index]) Validate() error {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+
+	return s.series.Validate()
+}
+
+func (s *ConcurrentSeries[Data, Index]) MarshalJSON() ([]byte, error) {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+
+	return s.series.MarshalJSON()
+}
+
+func (s *ConcurrentSeries[Data, Index]) MarshalBinary() ([]byte, error) {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+
+	return s.series.MarshalBinary()
+}
+
+// Snapshot can be read without locking the series.
+func (s *ConcurrentSeries[Data, Index]) Snapshot() (*SeriesSnapshot[Data, Index], error) {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+
+	return s.series.Snapshot()
+}
+
+func (s *ConcurrentSeries[Data, Index]) AddData(data []Data) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	return s.series.AddData(data)
+}
+
+func (s *ConcurrentSeries[Data, Index]) AddPeriod(periodStart, periodEnd Index, data []Data) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	return s.series.AddPeriod(periodStart, periodEnd, data)
+}
+
+func (s *ConcurrentSeries[Data, Index]) DeletePeriod(periodStart, periodEnd Index) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	return s.series.DeletePeriod(periodStart, periodEnd)
+}
+
+func (s *ConcurrentSeries[Data, Index]) SetRetention(policy *RetentionPolicy[Index]) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	return s.series.SetRetention(policy)
+}
+
+func (s *ConcurrentSeries[Data, Index]) Restore(state *SeriesState[Data, Index]) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	return s.series.Restore(state)
+}
+
+func (s *ConcurrentSeries[Data, Index]) SetValidateOnChange(validate bool) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	s.series.SetValidateOnChange(validate)
+}
+
+func (s *ConcurrentSeries[Data, Index]) UnmarshalJSON(b []byte) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	return s.series.UnmarshalJSON(b)
+}
+
+func (s *ConcurrentSeries[Data, Index]) UnmarshalBinary(b []byte) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	return s.series.UnmarshalBinary(b)
+}
+
+func (s *ConcurrentSeries[Data, Index]) SetCodec(codec Codec[Data, Index]) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	s.series.SetCodec(codec)
+}
+
+func (s *ConcurrentSeries[Data, Index]) SetWAL(wal *WAL[Data, Index]) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	s.series.SetWAL(wal)
+}
+
+func (s *ConcurrentSeries[Data, Index]) Checkpoint(snapshotPath string) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	return s.series.Checkpoint(snapshotPath)
+}
+
+func (s *ConcurrentSeries[Data, Index]) Replay(snapshotPath string, wal *WAL[Data, Index]) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	return s.series.Replay(snapshotPath, wal)
+}
+
+func (s *ConcurrentSeries[Data, Index]) SetMergePolicy(policy *MergePolicy[Data]) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	s.series.SetMergePolicy(policy)
+}
+
+func (s *ConcurrentSeries[Data, Index]) Column(name string, periodStart, periodEnd Index) (any, error) {
+	defer s.lockForRead()()
+
+	return s.series.Column(name, periodStart, periodEnd)
+}
+
+func (s *ConcurrentSeries[Data, Index]) SetTiering(policy *TieringPolicy[Data, Index]) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	err := s.series.SetTiering(policy)
+	s.tiered.Store(s.series.tiering != nil)
+
+	return err
+}
+
+func (s *ConcurrentSeries[Data, Index]) RestoreFileDataDir(dir string, codec Codec[Data, Index], opts FileDataOptions) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	return s.series.RestoreFileDataDir(dir, codec, opts)
+}
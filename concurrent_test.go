@@ -0,0 +1,70 @@
+package sparse_test
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/nnikolash/go-sparse"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConcurrentSeries_ReadWrite(t *testing.T) {
+	t.Parallel()
+
+	series := sparse.NewConcurrentSeries[int, int](
+		sparse.NewArrayData,
+		func(data *int) int { return *data },
+		func(idx1, idx2 int) int { return idx1 - idx2 },
+		func(smaller, bigger int) bool { return bigger-smaller == 1 },
+	)
+
+	err := series.AddData([]int{0})
+	require.NoError(t, err)
+
+	const writes = 200
+
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 1; i <= writes; i++ {
+			assert.NoError(t, series.AddPeriod(i*10-9, i*10, []int{i * 10}))
+			if i%10 == 0 {
+				assert.NoError(t, series.DeletePeriod(-1, i))
+			}
+		}
+	}()
+
+	for r := 0; r < 4; r++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < writes; i++ {
+				res, err := series.Get(i*10-15, i*10)
+				if err != nil {
+					var missingErr *sparse.MissingPeriodError[int]
+					assert.ErrorAs(t, err, &missingErr)
+				}
+				for j := 1; j < len(res); j++ {
+					assert.Less(t, res[j-1], res[j])
+				}
+
+				series.GetPeriodClosestFromEnd(i*10, true)
+				series.GetPeriod(i, i)
+				series.GetPeriodClosestFromStart(i*10, false)
+				series.MissingPeriods(0, writes*10)
+				_, _, err = series.GetAvailable(0, writes*10)
+				assert.NoError(t, err)
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	res, err := series.Get(writes*10-5, writes*10)
+	require.NoError(t, err)
+	require.Equal(t, []int{writes * 10}, res)
+	require.Len(t, series.Segments(), 1)
+}
@@ -2,19 +2,55 @@ package sparse
 
 import (
 	"fmt"
+	"iter"
 	"slices"
 	"sort"
+
+	"github.com/pkg/errors"
 )
 
 type SeriesData[Data any, Index any] interface {
 	Get(periodStart, periodEnd Index) ([]Data, error)
 	GetEndOpen(periodStart, periodEnd Index) ([]Data, error)
 	Merge(data []Data) error
+	// Returns single item, even if there are several items with the same index. Returning the whole group would change
+	// the signature of every existing storage, while Get for the boundary index already returns it - see FirstGroup of segment.
 	First(idx Index) (*Data, error)
+	// Returns single item, even if there are several items with the same index. Use LastGroup of segment to get all of them.
 	Last(idx Index) (*Data, error)
+	Delete(periodStart, periodEnd Index) error
 	//String() string
+}
+
+// Optionally implemented by storages, which are able to create immutable view of their current state
+// without copying all the data.
+type SnapshotableSeriesData[Data any, Index any] interface {
+	Snapshot() (SeriesData[Data, Index], error)
+}
+
+// Optionally implemented by storages, which are able to yield data lazily instead of allocating the whole result.
+// Returned sequences yield data in the closed period [ periodStart ; periodEnd ].
+type IterableSeriesData[Data any, Index any] interface {
+	All(periodStart, periodEnd Index) (iter.Seq[Data], error)
+	Backward(periodStart, periodEnd Index) (iter.Seq[Data], error)
+}
 
-	// TODO: Delete method (e.g. to use for cleanup of entries in db)
+// Optionally implemented by storages, which hold external resources (e.g. files).
+// Remove is called by series for storages of segments, which were merged, deleted or evicted by tiering.
+type RemovableSeriesData interface {
+	Remove() error
+}
+
+// Optionally implemented by storages, which persist bounds of their segment (e.g. to restore series from them).
+// SetBounds is called by segment every time its bounds change.
+type BoundedSeriesData[Index any] interface {
+	SetBounds(periodStart, periodEnd Index) error
+}
+
+// Optionally implemented by storages, which combine merged data with existing data themselves according to merge policy.
+// Segment does not remove existing data of merged period from such storage, unless series has its own merge policy.
+type MergingSeriesData[Data any] interface {
+	MergePolicy() *MergePolicy[Data]
 }
 
 type SeriesDataFactory[Data any, Index any] func(
@@ -23,24 +59,64 @@ type SeriesDataFactory[Data any, Index any] func(
 	periodStart, periodEnd Index, data []Data,
 ) (SeriesData[Data, Index], error)
 
+// Creates ArrayData, which allows multiple items with the same index. Data must be sorted.
+// Use NewArrayDataFactory with RejectDuplicates option to reject such items.
 func NewArrayData[Data any, Index any](
 	getIdx func(data *Data) Index,
 	idxCmp func(idx1, idx2 Index) int,
 	periodStart, periodEnd Index, data []Data,
 ) (SeriesData[Data, Index], error) {
-	return &ArrayData[Data, Index]{
+	return newArrayData(getIdx, idxCmp, data, ArrayDataOptions[Data]{})
+}
+
+var _ SeriesDataFactory[int, int] = NewArrayData
+
+type ArrayDataOptions[Data any] struct {
+	// Policy of combining merged data with existing data. Nil means that merged data replaces existing data in its range.
+	MergePolicy *MergePolicy[Data]
+	// Reject data with multiple items with the same index with ErrDuplicateIndex. Otherwise items with the same index
+	// are stored in the order they were merged, and merge replaces all items at the affected indexes as a group.
+	RejectDuplicates bool
+}
+
+// Creates factory of ArrayData with custom options.
+func NewArrayDataFactory[Data any, Index any](opts ArrayDataOptions[Data]) SeriesDataFactory[Data, Index] {
+	return func(
+		getIdx func(data *Data) Index,
+		idxCmp func(idx1, idx2 Index) int,
+		periodStart, periodEnd Index, data []Data,
+	) (SeriesData[Data, Index], error) {
+		return newArrayData(getIdx, idxCmp, data, opts)
+	}
+}
+
+// Creates factory of ArrayData, which combines merged data with existing data according to policy.
+// Series does not apply the policy, if it has merge policy of its own.
+// Same as NewArrayData, it allows multiple items with the same index.
+func NewArrayDataWithPolicy[Data any, Index any](policy *MergePolicy[Data]) SeriesDataFactory[Data, Index] {
+	return NewArrayDataFactory[Data, Index](ArrayDataOptions[Data]{MergePolicy: policy})
+}
+
+func newArrayData[Data any, Index any](getIdx func(data *Data) Index, idxCmp func(idx1, idx2 Index) int, data []Data, opts ArrayDataOptions[Data]) (*ArrayData[Data, Index], error) {
+	s := &ArrayData[Data, Index]{
 		getIdx: getIdx,
 		idxCmp: idxCmp,
 		data:   data,
-	}, nil
-}
+		opts:   opts,
+	}
 
-var _ SeriesDataFactory[int, int] = NewArrayData
+	if err := s.checkDuplicates(data); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
 
 type ArrayData[Data any, Index any] struct {
 	getIdx func(data *Data) Index
 	idxCmp func(idx1, idx2 Index) int
 	data   []Data
+	opts   ArrayDataOptions[Data]
 }
 
 func (s *ArrayData[Data, Index]) First(idx Index) (*Data, error) {
@@ -64,15 +140,14 @@ func (s *ArrayData[Data, Index]) Last(idx Index) (*Data, error) {
 }
 
 func (s *ArrayData[Data, Index]) Get(periodStart, periodEnd Index) ([]Data, error) {
-	return s.get(periodStart, periodEnd, false)
+	return s.get(periodStart, periodEnd, false), nil
 }
 
 func (s *ArrayData[Data, Index]) GetEndOpen(periodStart, periodEnd Index) ([]Data, error) {
-	res, err := s.get(periodStart, periodEnd, true)
-	return res, err
+	return s.get(periodStart, periodEnd, true), nil
 }
 
-func (s *ArrayData[Data, Index]) get(periodStart, periodEnd Index, endOpen bool) ([]Data, error) {
+func (s *ArrayData[Data, Index]) get(periodStart, periodEnd Index, endOpen bool) []Data {
 	dataStartIdx := s.getStartIdx(periodStart)
 
 	var dataEndIdx int
@@ -82,20 +157,12 @@ func (s *ArrayData[Data, Index]) get(periodStart, periodEnd Index, endOpen bool)
 		dataEndIdx = s.getEndIdx(periodEnd)
 	}
 
+	// Start index is within [ 0 ; len ] and end index is within [ -1 ; len - 1 ], so non-empty range is always inside data
 	if dataStartIdx > dataEndIdx {
-		return []Data{}, nil
-	}
-
-	if dataStartIdx == -1 || dataEndIdx == -1 {
-		panic(fmt.Errorf("data is not sorted - could find range [%v; %v] inside data %v: dataStartIdx = %v, dataEndIdx = %v",
-			periodStart, periodEnd, s.data, dataStartIdx, dataEndIdx))
-	}
-
-	if dataEndIdx == len(s.data) {
-		panic(fmt.Errorf("data is not sorted - could not find end index: periodEnd = %v, data = %v", periodEnd, s.data))
+		return []Data{}
 	}
 
-	return s.data[dataStartIdx : dataEndIdx+1 : dataEndIdx+1], nil
+	return s.data[dataStartIdx : dataEndIdx+1 : dataEndIdx+1]
 }
 
 func (s *ArrayData[Data, Index]) getStartIdx(periodStart Index) int {
@@ -125,16 +192,32 @@ func (s *ArrayData[Data, Index]) getEndIdxOpen(periodEnd Index) int {
 	return endIdx - 1
 }
 
+// Policy of combining merged data with existing data. Nil means that merged data replaces existing data in its range.
+func (s *ArrayData[Data, Index]) MergePolicy() *MergePolicy[Data] {
+	return s.opts.MergePolicy
+}
+
+var _ MergingSeriesData[int] = &ArrayData[int, int]{}
+
 func (s *ArrayData[Data, Index]) Merge(data []Data) error {
 	if len(data) == 0 {
 		return nil
 	}
 
+	if err := s.checkDuplicates(data); err != nil {
+		return err
+	}
+
 	if len(s.data) == 0 {
 		s.data = slices.Clone(data)
 		return nil
 	}
 
+	if !s.opts.MergePolicy.isOverwrite() {
+		old := s.get(s.getIdx(&data[0]), s.getIdx(&data[len(data)-1]), false)
+		data = combineData(s.opts.MergePolicy, s.getIdx, s.idxCmp, old, data)
+	}
+
 	var oldDataBeforeNewData []Data
 	var oldDataAfterNewData []Data
 
@@ -169,6 +252,107 @@ func (s *ArrayData[Data, Index]) Merge(data []Data) error {
 	return nil
 }
 
+func (s *ArrayData[Data, Index]) checkDuplicates(data []Data) error {
+	if !s.opts.RejectDuplicates {
+		return nil
+	}
+
+	for i := 1; i < len(data); i++ {
+		idx := s.getIdx(&data[i])
+		if s.idxCmp(s.getIdx(&data[i-1]), idx) == 0 {
+			return errors.Wrapf(ErrDuplicateIndex, "index %v", idx)
+		}
+	}
+
+	return nil
+}
+
+func (s *ArrayData[Data, Index]) Delete(periodStart, periodEnd Index) error {
+	dataStartIdx := s.getStartIdx(periodStart)
+	dataEndIdx := s.getEndIdx(periodEnd)
+
+	if dataStartIdx > dataEndIdx {
+		return nil
+	}
+
+	if dataStartIdx == 0 && dataEndIdx == len(s.data)-1 {
+		s.data = nil
+		return nil
+	}
+
+	remaining := make([]Data, 0, len(s.data)-(dataEndIdx-dataStartIdx+1))
+	remaining = append(remaining, s.data[:dataStartIdx]...)
+	remaining = append(remaining, s.data[dataEndIdx+1:]...)
+	s.data = remaining
+
+	return nil
+}
+
+func (s *ArrayData[Data, Index]) All(periodStart, periodEnd Index) (iter.Seq[Data], error) {
+	data := s.get(periodStart, periodEnd, false)
+
+	return func(yield func(Data) bool) {
+		for i := range data {
+			if !yield(data[i]) {
+				return
+			}
+		}
+	}, nil
+}
+
+func (s *ArrayData[Data, Index]) Backward(periodStart, periodEnd Index) (iter.Seq[Data], error) {
+	data := s.get(periodStart, periodEnd, false)
+
+	return func(yield func(Data) bool) {
+		for i := len(data) - 1; i >= 0; i-- {
+			if !yield(data[i]) {
+				return
+			}
+		}
+	}, nil
+}
+
+var _ IterableSeriesData[int, int] = &ArrayData[int, int]{}
+
+// Data array is never modified in place, so snapshot just shares it.
+func (s *ArrayData[Data, Index]) Snapshot() (SeriesData[Data, Index], error) {
+	return &ArrayData[Data, Index]{
+		getIdx: s.getIdx,
+		idxCmp: s.idxCmp,
+		data:   s.data,
+		opts:   s.opts,
+	}, nil
+}
+
+var _ SnapshotableSeriesData[int, int] = &ArrayData[int, int]{}
+
 func (s *ArrayData[Data, Index]) String() string {
 	return fmt.Sprintf("%v", s.data)
 }
+
+// Returns range [ lo ; hi ) of sorted non-overlapping chunks, which intersect the period.
+func findChunks[Index any](n int, idxCmp func(idx1, idx2 Index) int, periodStart, periodEnd Index, bounds func(i int) (first, last Index)) (lo, hi int) {
+	lo = sort.Search(n, func(i int) bool {
+		_, last := bounds(i)
+		return idxCmp(last, periodStart) >= 0
+	})
+
+	hi = lo + sort.Search(n-lo, func(i int) bool {
+		first, _ := bounds(lo + i)
+		return idxCmp(first, periodEnd) > 0
+	})
+
+	return lo, hi
+}
+
+// Returns sorted data before and after the period.
+func splitByPeriod[Data any, Index any](data []Data, getIdx func(data *Data) Index, idxCmp func(idx1, idx2 Index) int, periodStart, periodEnd Index) (before, after []Data) {
+	start := sort.Search(len(data), func(i int) bool {
+		return idxCmp(getIdx(&data[i]), periodStart) >= 0
+	})
+	end := sort.Search(len(data), func(i int) bool {
+		return idxCmp(getIdx(&data[i]), periodEnd) > 0
+	})
+
+	return data[:start], data[end:]
+}
@@ -0,0 +1,99 @@
+package sparse_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/nnikolash/go-sparse"
+	"github.com/nnikolash/go-sparse/sparsetest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestArrayData_Conformance(t *testing.T) {
+	t.Parallel()
+
+	sparsetest.RunSeriesDataConformance(t, sparse.NewArrayData[sparsetest.Item, int], sparsetest.ItemFixture())
+}
+
+func TestArrayData_UniqueConformance(t *testing.T) {
+	t.Parallel()
+
+	sparsetest.RunSeriesDataConformance(t, sparse.NewArrayDataFactory[sparsetest.Item, int](sparse.ArrayDataOptions[sparsetest.Item]{RejectDuplicates: true}), sparsetest.ItemFixture())
+}
+
+type quote struct {
+	Time  time.Time
+	Price string
+}
+
+func TestArrayData_TimeIndexConformance(t *testing.T) {
+	t.Parallel()
+
+	sparsetest.RunSeriesDataConformance(t, sparse.NewArrayData[quote, time.Time], sparsetest.Fixture[quote, time.Time]{
+		GetIdx: func(data *quote) time.Time { return data.Time },
+		IdxCmp: func(idx1, idx2 time.Time) int { return idx1.Compare(idx2) },
+		Index:  func(pos int) time.Time { return time.Unix(int64(pos)*60, 0) },
+		Item: func(pos int, version int) quote {
+			return quote{Time: time.Unix(int64(pos)*60, 0), Price: fmt.Sprintf("%v.%v", pos, version)}
+		},
+	})
+}
+
+func TestArrayData_Duplicates(t *testing.T) {
+	t.Parallel()
+
+	// Duplicates are allowed by default
+	data, err := sparse.NewArrayData(sparsetest.ItemIdx, sparsetest.ItemIdxCmp, 1, 5, []sparsetest.Item{
+		item(1, 1), item(1, 2), item(3, 1), item(3, 2), item(3, 3), item(5, 1), item(5, 2),
+	})
+	require.NoError(t, err)
+
+	res, err := data.Get(1, 3)
+	require.NoError(t, err)
+	require.Equal(t, []sparsetest.Item{item(1, 1), item(1, 2), item(3, 1), item(3, 2), item(3, 3)}, res)
+
+	res, err = data.GetEndOpen(1, 3)
+	require.NoError(t, err)
+	require.Equal(t, []sparsetest.Item{item(1, 1), item(1, 2)}, res)
+
+	// Whole groups at indexes 3 and 5 are replaced
+	require.NoError(t, data.Merge([]sparsetest.Item{item(3, 10), item(4, 10), item(5, 10), item(5, 20)}))
+	res, err = data.Get(0, 10)
+	require.NoError(t, err)
+	require.Equal(t, []sparsetest.Item{item(1, 1), item(1, 2), item(3, 10), item(4, 10), item(5, 10), item(5, 20)}, res)
+
+	require.NoError(t, data.Delete(1, 1))
+	res, err = data.Get(0, 10)
+	require.NoError(t, err)
+	require.Equal(t, []sparsetest.Item{item(3, 10), item(4, 10), item(5, 10), item(5, 20)}, res)
+}
+
+func TestArrayData_DuplicatesNotAllowed(t *testing.T) {
+	t.Parallel()
+
+	factories := map[string]sparse.SeriesDataFactory[sparsetest.Item, int]{
+		"overwrite": sparse.NewArrayDataFactory[sparsetest.Item, int](sparse.ArrayDataOptions[sparsetest.Item]{RejectDuplicates: true}),
+		"policy": sparse.NewArrayDataFactory[sparsetest.Item, int](sparse.ArrayDataOptions[sparsetest.Item]{
+			MergePolicy:      sparse.MergeKeepExisting[sparsetest.Item](),
+			RejectDuplicates: true,
+		}),
+	}
+
+	for name, factory := range factories {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := factory(sparsetest.ItemIdx, sparsetest.ItemIdxCmp, 1, 3, []sparsetest.Item{item(1, 1), item(2, 1), item(2, 2)})
+			require.ErrorIs(t, err, sparse.ErrDuplicateIndex)
+
+			data, err := factory(sparsetest.ItemIdx, sparsetest.ItemIdxCmp, 1, 3, sparsetest.Items(1, 1, 2, 3))
+			require.NoError(t, err)
+
+			require.ErrorIs(t, data.Merge([]sparsetest.Item{item(2, 1), item(2, 2)}), sparse.ErrDuplicateIndex)
+			res, err := data.Get(1, 3)
+			require.NoError(t, err)
+			require.Equal(t, sparsetest.Items(1, 1, 2, 3), res)
+		})
+	}
+}
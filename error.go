@@ -1,6 +1,23 @@
 package sparse
 
-import "fmt"
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	// Matches any UnsortedDataError.
+	ErrUnsortedData = errors.New("data is not sorted")
+	// Matches any StorageCorruptedError.
+	ErrStorageCorrupted = errors.New("storage is corrupted")
+	// Data contains multiple items with the same index, but storage does not allow it.
+	ErrDuplicateIndex = errors.New("duplicate index")
+	// Storage does not support modifications.
+	ErrReadOnly = errors.New("storage is read-only")
+	// Storage was closed and can not be used anymore.
+	ErrClosed = errors.New("storage is closed")
+)
 
 type MissingPeriodError[Index any] struct {
 	PeriodStart Index
@@ -10,3 +27,37 @@ type MissingPeriodError[Index any] struct {
 func (e *MissingPeriodError[Index]) Error() string {
 	return fmt.Sprintf("series missing period: %v - %v", e.PeriodStart, e.PeriodEnd)
 }
+
+// Storage returned data, which is not sorted or does not belong to the requested period.
+type UnsortedDataError[Index any] struct {
+	PeriodStart Index
+	PeriodEnd   Index
+	Details     string
+}
+
+func (e *UnsortedDataError[Index]) Error() string {
+	return fmt.Sprintf("data is not sorted in period %v - %v: %v", e.PeriodStart, e.PeriodEnd, e.Details)
+}
+
+func (e *UnsortedDataError[Index]) Is(target error) bool {
+	return target == ErrUnsortedData
+}
+
+// State of the series or its segment is inconsistent, e.g. after restoring it from corrupted persisted state.
+type StorageCorruptedError[Index any] struct {
+	PeriodStart Index
+	PeriodEnd   Index
+	Err         error
+}
+
+func (e *StorageCorruptedError[Index]) Error() string {
+	return fmt.Sprintf("storage is corrupted in period %v - %v: %v", e.PeriodStart, e.PeriodEnd, e.Err)
+}
+
+func (e *StorageCorruptedError[Index]) Unwrap() error {
+	return e.Err
+}
+
+func (e *StorageCorruptedError[Index]) Is(target error) bool {
+	return target == ErrStorageCorrupted
+}
@@ -0,0 +1,83 @@
+package sparse_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/nnikolash/go-sparse"
+	"github.com/stretchr/testify/require"
+)
+
+// Storage, which ignores requested period and returns all its data.
+type unboundedData struct {
+	sparse.SeriesData[int, int]
+	data []int
+}
+
+func (d *unboundedData) Get(periodStart, periodEnd int) ([]int, error) {
+	return d.data, nil
+}
+
+func TestSeries_UnsortedDataError(t *testing.T) {
+	t.Parallel()
+
+	series := sparse.NewSeries[int, int](
+		func(getIdx func(data *int) int, idxCmp func(idx1, idx2 int) int, periodStart, periodEnd int, data []int) (sparse.SeriesData[int, int], error) {
+			storage, err := sparse.NewArrayData(getIdx, idxCmp, periodStart, periodEnd, data)
+			if err != nil {
+				return nil, err
+			}
+			return &unboundedData{SeriesData: storage, data: data}, nil
+		},
+		func(data *int) int { return *data },
+		func(idx1, idx2 int) int { return idx1 - idx2 },
+		nil,
+	)
+
+	require.NoError(t, series.AddData([]int{10, 20, 30}))
+
+	_, err := series.Get(15, 25)
+	require.Error(t, err)
+	require.ErrorIs(t, err, sparse.ErrUnsortedData)
+	require.NotErrorIs(t, err, sparse.ErrStorageCorrupted)
+
+	var unsortedErr *sparse.UnsortedDataError[int]
+	require.True(t, errors.As(err, &unsortedErr))
+	require.Equal(t, 15, unsortedErr.PeriodStart)
+	require.Equal(t, 25, unsortedErr.PeriodEnd)
+
+	var missingErr *sparse.MissingPeriodError[int]
+	require.False(t, errors.As(err, &missingErr))
+
+	require.NotPanics(t, func() { series.SegmentsString() })
+}
+
+func TestSeries_StorageCorruptedError(t *testing.T) {
+	t.Parallel()
+
+	series := intSparseSeries()
+	// Unsorted data within segment bounds is found only by full validation
+	series.SetValidateOnChange(true)
+
+	err := series.Restore(&sparse.SeriesState[int, int]{Segments: []*sparse.SeriesSegmentFields[int, int]{
+		segmentFields(10, 20, false, 10),
+		segmentFields(30, 40, false, 35, 32),
+	}})
+	require.Error(t, err)
+	require.ErrorIs(t, err, sparse.ErrStorageCorrupted)
+	require.ErrorIs(t, err, sparse.ErrUnsortedData)
+
+	var corruptedErr *sparse.StorageCorruptedError[int]
+	require.True(t, errors.As(err, &corruptedErr))
+	require.Equal(t, 30, corruptedErr.PeriodStart)
+	require.Equal(t, 40, corruptedErr.PeriodEnd)
+
+	err = series.Restore(&sparse.SeriesState[int, int]{Segments: []*sparse.SeriesSegmentFields[int, int]{
+		segmentFields(10, 20, false, 10),
+		segmentFields(15, 40, false, 35),
+	}})
+	require.ErrorIs(t, err, sparse.ErrStorageCorrupted)
+	require.True(t, errors.As(err, &corruptedErr))
+	require.Equal(t, 10, corruptedErr.PeriodStart)
+	require.Equal(t, 40, corruptedErr.PeriodEnd)
+}
@@ -0,0 +1,744 @@
+package sparse
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+const fileDataLogPattern = "segment-*.log"
+
+const (
+	fileDataRecordMerge  = 1
+	fileDataRecordDelete = 2
+	fileDataRecordBounds = 3
+
+	defaultFileDataCompactAfter = 100
+	defaultFileDataChunkSize    = 1024
+)
+
+type FileDataOptions struct {
+	// Number of superseded records, after which log is compacted automatically.
+	// Zero means default value, negative value disables automatic compaction.
+	CompactAfter int
+	// Maximum number of items in single record written by compaction. Zero means default value.
+	ChunkSize int
+	// Sync file after each write.
+	Sync bool
+}
+
+// Creates factory of storages, each of which keeps its data and bounds of its segment in separate append-only log file inside dir.
+// Series, which uses such storages, can be restored from dir using RestoreFileDataDir.
+func NewFileDataFactory[Data any, Index any](dir string, codec Codec[Data, Index], opts FileDataOptions) SeriesDataFactory[Data, Index] {
+	return func(
+		getIdx func(data *Data) Index,
+		idxCmp func(idx1, idx2 Index) int,
+		periodStart, periodEnd Index, data []Data,
+	) (SeriesData[Data, Index], error) {
+		file, err := os.CreateTemp(dir, fileDataLogPattern)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to create log file")
+		}
+		if err := file.Close(); err != nil {
+			return nil, errors.Wrap(err, "failed to create log file")
+		}
+
+		s := newFileData(file.Name(), getIdx, idxCmp, codec, opts)
+
+		// Bounds are written after data, so log without bounds is left only by interrupted creation
+		if err := s.Merge(data); err != nil {
+			s.Remove()
+			return nil, err
+		}
+		if err := s.SetBounds(periodStart, periodEnd); err != nil {
+			s.Remove()
+			return nil, err
+		}
+
+		return s, nil
+	}
+}
+
+// Opens existing log file. If the file ends with torn or corrupted record, it is truncated to the last valid record.
+func OpenFileData[Data any, Index any](
+	path string,
+	getIdx func(data *Data) Index,
+	idxCmp func(idx1, idx2 Index) int,
+	codec Codec[Data, Index],
+	opts FileDataOptions,
+) (*FileData[Data, Index], error) {
+	s := newFileData(path, getIdx, idxCmp, codec, opts)
+
+	size, err := recoverRecords(path, func(offset int64, payload []byte) error {
+		s.records++
+		return s.replayRecord(offset, payload)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.size = size
+
+	return s, nil
+}
+
+func newFileData[Data any, Index any](
+	path string,
+	getIdx func(data *Data) Index,
+	idxCmp func(idx1, idx2 Index) int,
+	codec Codec[Data, Index],
+	opts FileDataOptions,
+) *FileData[Data, Index] {
+	if opts.CompactAfter == 0 {
+		opts.CompactAfter = defaultFileDataCompactAfter
+	}
+	if opts.ChunkSize <= 0 {
+		opts.ChunkSize = defaultFileDataChunkSize
+	}
+
+	return &FileData[Data, Index]{
+		path:         path,
+		getIdx:       getIdx,
+		idxCmp:       idxCmp,
+		codec:        codec,
+		opts:         opts,
+		cachedOffset: -1,
+	}
+}
+
+var _ SeriesDataFactory[int, int] = NewFileDataFactory[int, int]("", JSONCodec[int, int]{}, FileDataOptions{})
+
+// Storage, which keeps data in append-only log file. Each Merge appends record, which supersedes older data,
+// each Delete appends record with deleted period, and each change of segment bounds appends record with new bounds.
+// Memory keeps only index of records, which still contain actual data. File is opened only for the duration of each operation.
+type FileData[Data any, Index any] struct {
+	path   string
+	getIdx func(data *Data) Index
+	idxCmp func(idx1, idx2 Index) int
+	codec  Codec[Data, Index]
+	opts   FileDataOptions
+
+	size    int64
+	records int
+	chunks  []fileDataChunk[Index]
+	bounds  *PeriodBounds[Index]
+
+	// Decoded data of the last read record. Reads may run concurrently, so it has its own lock.
+	cacheMtx     sync.Mutex
+	cachedOffset int64
+	cachedData   []Data
+}
+
+// Part of record, which still contains actual data.
+type fileDataChunk[Index any] struct {
+	offset int64
+	// Range [ lo ; hi ) of actual items within record
+	lo, hi      int
+	first, last Index
+}
+
+func (s *FileData[Data, Index]) Path() string {
+	return s.path
+}
+
+func (s *FileData[Data, Index]) First(idx Index) (*Data, error) {
+	if len(s.chunks) == 0 {
+		return nil, nil
+	}
+
+	chunk := s.chunks[0]
+
+	data, err := s.readChunk(chunk)
+	if err != nil {
+		return nil, err
+	}
+
+	v := data[0]
+
+	return &v, nil
+}
+
+func (s *FileData[Data, Index]) Last(idx Index) (*Data, error) {
+	if len(s.chunks) == 0 {
+		return nil, nil
+	}
+
+	chunk := s.chunks[len(s.chunks)-1]
+
+	data, err := s.readChunk(chunk)
+	if err != nil {
+		return nil, err
+	}
+
+	v := data[len(data)-1]
+
+	return &v, nil
+}
+
+func (s *FileData[Data, Index]) Get(periodStart, periodEnd Index) ([]Data, error) {
+	return s.get(periodStart, periodEnd, false)
+}
+
+func (s *FileData[Data, Index]) GetEndOpen(periodStart, periodEnd Index) ([]Data, error) {
+	return s.get(periodStart, periodEnd, true)
+}
+
+func (s *FileData[Data, Index]) get(periodStart, periodEnd Index, endOpen bool) ([]Data, error) {
+	res := []Data{}
+
+	firstChunk := sort.Search(len(s.chunks), func(i int) bool {
+		return s.idxCmp(s.chunks[i].last, periodStart) >= 0
+	})
+
+	for _, chunk := range s.chunks[firstChunk:] {
+		if s.idxCmp(chunk.first, periodEnd) > 0 || (endOpen && s.idxCmp(chunk.first, periodEnd) == 0) {
+			break
+		}
+
+		data, err := s.readChunk(chunk)
+		if err != nil {
+			return nil, err
+		}
+
+		for i := range data {
+			idx := s.getIdx(&data[i])
+			if s.idxCmp(idx, periodStart) < 0 {
+				continue
+			}
+			if s.idxCmp(idx, periodEnd) > 0 || (endOpen && s.idxCmp(idx, periodEnd) == 0) {
+				break
+			}
+
+			res = append(res, data[i])
+		}
+	}
+
+	return res, nil
+}
+
+func (s *FileData[Data, Index]) Merge(data []Data) error {
+	if len(data) == 0 {
+		return nil
+	}
+
+	encoded, err := s.codec.EncodeData(data)
+	if err != nil {
+		return err
+	}
+
+	offset, err := s.appendRecord(append([]byte{fileDataRecordMerge}, encoded...))
+	if err != nil {
+		return err
+	}
+
+	if err := s.applyMerge(offset, slices.Clone(data)); err != nil {
+		return err
+	}
+
+	return s.compactIfNeeded()
+}
+
+// Records bounds of the segment, which the storage belongs to.
+func (s *FileData[Data, Index]) SetBounds(periodStart, periodEnd Index) error {
+	if s.bounds != nil && s.idxCmp(s.bounds.PeriodStart, periodStart) == 0 && s.idxCmp(s.bounds.PeriodEnd, periodEnd) == 0 {
+		return nil
+	}
+
+	payload, err := s.encodePeriod(fileDataRecordBounds, periodStart, periodEnd)
+	if err != nil {
+		return err
+	}
+
+	if _, err := s.appendRecord(payload); err != nil {
+		return err
+	}
+
+	s.bounds = &PeriodBounds[Index]{PeriodStart: periodStart, PeriodEnd: periodEnd}
+
+	return s.compactIfNeeded()
+}
+
+var _ BoundedSeriesData[int] = &FileData[int, int]{}
+
+func (s *FileData[Data, Index]) Delete(periodStart, periodEnd Index) error {
+	if !s.hasDataWithin(periodStart, periodEnd) {
+		return nil
+	}
+
+	payload, err := s.encodePeriod(fileDataRecordDelete, periodStart, periodEnd)
+	if err != nil {
+		return err
+	}
+
+	if _, err := s.appendRecord(payload); err != nil {
+		return err
+	}
+
+	if err := s.cutOut(periodStart, periodEnd); err != nil {
+		return err
+	}
+
+	return s.compactIfNeeded()
+}
+
+// Rewrites log file, so that it contains only actual data.
+func (s *FileData[Data, Index]) Compact() error {
+	var b []byte
+	var chunks []fileDataChunk[Index]
+	var batch []Data
+
+	if s.bounds != nil {
+		payload, err := s.encodePeriod(fileDataRecordBounds, s.bounds.PeriodStart, s.bounds.PeriodEnd)
+		if err != nil {
+			return err
+		}
+
+		b = appendRecord(b, payload)
+	}
+
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+
+		encoded, err := s.codec.EncodeData(batch)
+		if err != nil {
+			return err
+		}
+
+		chunks = append(chunks, fileDataChunk[Index]{
+			offset: int64(len(b)),
+			lo:     0,
+			hi:     len(batch),
+			first:  s.getIdx(&batch[0]),
+			last:   s.getIdx(&batch[len(batch)-1]),
+		})
+		b = appendRecord(b, append([]byte{fileDataRecordMerge}, encoded...))
+		batch = batch[:0]
+
+		return nil
+	}
+
+	for _, chunk := range s.chunks {
+		data, err := s.readChunk(chunk)
+		if err != nil {
+			return err
+		}
+
+		for len(data) != 0 {
+			n := min(len(data), s.opts.ChunkSize-len(batch))
+			batch = append(batch, data[:n]...)
+			data = data[n:]
+
+			if len(batch) == s.opts.ChunkSize {
+				if err := flush(); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	if err := flush(); err != nil {
+		return err
+	}
+
+	if err := replaceFile(s.path, b, s.opts.Sync); err != nil {
+		return err
+	}
+
+	s.size = int64(len(b))
+	s.records = len(chunks)
+	if s.bounds != nil {
+		s.records++
+	}
+	s.chunks = chunks
+	s.setCachedRecord(-1, nil)
+
+	return nil
+}
+
+// Removes log file. Storage must not be used after that.
+func (s *FileData[Data, Index]) Remove() error {
+	if err := os.Remove(s.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return errors.Wrapf(err, "failed to remove file %v", s.path)
+	}
+
+	return nil
+}
+
+var _ RemovableSeriesData = &FileData[int, int]{}
+
+func (s *FileData[Data, Index]) String() string {
+	return s.path
+}
+
+func (s *FileData[Data, Index]) appendRecord(payload []byte) (offset int64, _ error) {
+	b := appendRecord(nil, payload)
+
+	if err := appendToFile(s.path, b, s.opts.Sync); err != nil {
+		return 0, err
+	}
+
+	offset = s.size
+	s.size += int64(len(b))
+	s.records++
+
+	return offset, nil
+}
+
+func (s *FileData[Data, Index]) encodePeriod(recordType byte, periodStart, periodEnd Index) ([]byte, error) {
+	encodedStart, err := s.codec.EncodeIndex(periodStart)
+	if err != nil {
+		return nil, err
+	}
+
+	encodedEnd, err := s.codec.EncodeIndex(periodEnd)
+	if err != nil {
+		return nil, err
+	}
+
+	payload := []byte{recordType}
+	payload = appendBytes(payload, encodedStart)
+	payload = appendBytes(payload, encodedEnd)
+
+	return payload, nil
+}
+
+func (s *FileData[Data, Index]) decodePeriod(offset int64, payload []byte) (periodStart, periodEnd Index, _ error) {
+	r := bytes.NewReader(payload)
+
+	encodedStart, err := readBytes(r)
+	if err != nil {
+		return periodStart, periodEnd, errors.Wrapf(err, "failed to read record at %v in log %v", offset, s.path)
+	}
+	encodedEnd, err := readBytes(r)
+	if err != nil {
+		return periodStart, periodEnd, errors.Wrapf(err, "failed to read record at %v in log %v", offset, s.path)
+	}
+
+	if periodStart, err = s.codec.DecodeIndex(encodedStart); err != nil {
+		return periodStart, periodEnd, err
+	}
+	if periodEnd, err = s.codec.DecodeIndex(encodedEnd); err != nil {
+		return periodStart, periodEnd, err
+	}
+
+	return periodStart, periodEnd, nil
+}
+
+func (s *FileData[Data, Index]) replayRecord(offset int64, payload []byte) error {
+	if len(payload) == 0 {
+		return errors.Errorf("empty record at %v in log %v", offset, s.path)
+	}
+
+	switch payload[0] {
+	case fileDataRecordMerge:
+		data, err := s.codec.DecodeData(payload[1:])
+		if err != nil {
+			return err
+		}
+		if len(data) == 0 {
+			return nil
+		}
+
+		return s.applyMerge(offset, data)
+	case fileDataRecordDelete:
+		periodStart, periodEnd, err := s.decodePeriod(offset, payload[1:])
+		if err != nil {
+			return err
+		}
+
+		return s.cutOut(periodStart, periodEnd)
+	case fileDataRecordBounds:
+		periodStart, periodEnd, err := s.decodePeriod(offset, payload[1:])
+		if err != nil {
+			return err
+		}
+
+		s.bounds = &PeriodBounds[Index]{PeriodStart: periodStart, PeriodEnd: periodEnd}
+
+		return nil
+	default:
+		return errors.Errorf("unknown record type %v at %v in log %v", payload[0], offset, s.path)
+	}
+}
+
+// Registers data of record written at offset, which supersedes older data within its bounds.
+func (s *FileData[Data, Index]) applyMerge(offset int64, data []Data) error {
+	first := s.getIdx(&data[0])
+	last := s.getIdx(&data[len(data)-1])
+
+	if err := s.cutOut(first, last); err != nil {
+		return err
+	}
+
+	pos := sort.Search(len(s.chunks), func(i int) bool {
+		return s.idxCmp(s.chunks[i].first, last) > 0
+	})
+
+	s.chunks = slices.Insert(s.chunks, pos, fileDataChunk[Index]{
+		offset: offset,
+		lo:     0,
+		hi:     len(data),
+		first:  first,
+		last:   last,
+	})
+
+	s.setCachedRecord(offset, data)
+
+	return nil
+}
+
+// Removes data within [ periodStart ; periodEnd ] from index of actual data.
+func (s *FileData[Data, Index]) cutOut(periodStart, periodEnd Index) error {
+	from := sort.Search(len(s.chunks), func(i int) bool {
+		return s.idxCmp(s.chunks[i].last, periodStart) >= 0
+	})
+	to := sort.Search(len(s.chunks), func(i int) bool {
+		return s.idxCmp(s.chunks[i].first, periodEnd) > 0
+	})
+
+	if from >= to {
+		return nil
+	}
+
+	remaining := make([]fileDataChunk[Index], 0, 2)
+
+	if s.idxCmp(s.chunks[from].first, periodStart) < 0 {
+		left, err := s.trimChunk(s.chunks[from], func(idx Index) bool { return s.idxCmp(idx, periodStart) < 0 })
+		if err != nil {
+			return err
+		}
+		remaining = append(remaining, left)
+	}
+
+	if s.idxCmp(s.chunks[to-1].last, periodEnd) > 0 {
+		right, err := s.trimChunk(s.chunks[to-1], func(idx Index) bool { return s.idxCmp(idx, periodEnd) > 0 })
+		if err != nil {
+			return err
+		}
+		remaining = append(remaining, right)
+	}
+
+	s.chunks = slices.Replace(s.chunks, from, to, remaining...)
+
+	return nil
+}
+
+// Returns part of chunk, which consists of items matching keep. Matching items must be adjacent.
+func (s *FileData[Data, Index]) trimChunk(chunk fileDataChunk[Index], keep func(idx Index) bool) (fileDataChunk[Index], error) {
+	data, err := s.readChunk(chunk)
+	if err != nil {
+		return chunk, err
+	}
+
+	lo := 0
+	for lo < len(data) && !keep(s.getIdx(&data[lo])) {
+		lo++
+	}
+
+	hi := lo
+	for hi < len(data) && keep(s.getIdx(&data[hi])) {
+		hi++
+	}
+
+	return fileDataChunk[Index]{
+		offset: chunk.offset,
+		lo:     chunk.lo + lo,
+		hi:     chunk.lo + hi,
+		first:  s.getIdx(&data[lo]),
+		last:   s.getIdx(&data[hi-1]),
+	}, nil
+}
+
+func (s *FileData[Data, Index]) hasDataWithin(periodStart, periodEnd Index) bool {
+	i := sort.Search(len(s.chunks), func(i int) bool {
+		return s.idxCmp(s.chunks[i].last, periodStart) >= 0
+	})
+
+	// Chunks are not empty, but data may still be between items of the chunk, so this is conservative check.
+	return i < len(s.chunks) && s.idxCmp(s.chunks[i].first, periodEnd) <= 0
+}
+
+// Returns actual items of chunk.
+func (s *FileData[Data, Index]) readChunk(chunk fileDataChunk[Index]) ([]Data, error) {
+	data, cached := s.getCachedRecord(chunk.offset)
+	if !cached {
+		payload, err := readRecordAt(s.path, chunk.offset)
+		if err != nil {
+			return nil, err
+		}
+		if len(payload) == 0 || payload[0] != fileDataRecordMerge {
+			return nil, errors.Errorf("unexpected record at %v in log %v", chunk.offset, s.path)
+		}
+
+		data, err = s.codec.DecodeData(payload[1:])
+		if err != nil {
+			return nil, err
+		}
+
+		s.setCachedRecord(chunk.offset, data)
+	}
+
+	if chunk.hi > len(data) {
+		return nil, errors.Errorf("record at %v in log %v has %v items, but expected at least %v", chunk.offset, s.path, len(data), chunk.hi)
+	}
+
+	return data[chunk.lo:chunk.hi], nil
+}
+
+func (s *FileData[Data, Index]) getCachedRecord(offset int64) ([]Data, bool) {
+	s.cacheMtx.Lock()
+	defer s.cacheMtx.Unlock()
+
+	return s.cachedData, s.cachedOffset == offset
+}
+
+func (s *FileData[Data, Index]) setCachedRecord(offset int64, data []Data) {
+	s.cacheMtx.Lock()
+	defer s.cacheMtx.Unlock()
+
+	s.cachedOffset = offset
+	s.cachedData = data
+}
+
+func (s *FileData[Data, Index]) compactIfNeeded() error {
+	if s.opts.CompactAfter < 0 {
+		return nil
+	}
+
+	liveRecords := make(map[int64]struct{}, len(s.chunks))
+	for _, chunk := range s.chunks {
+		liveRecords[chunk.offset] = struct{}{}
+	}
+
+	live := len(liveRecords)
+	if s.bounds != nil {
+		live++
+	}
+
+	if s.records-live < s.opts.CompactAfter {
+		return nil
+	}
+
+	return s.Compact()
+}
+
+// Removes data outside of recorded bounds, which is left by interrupted change of the segment.
+func (s *FileData[Data, Index]) trimToBounds() error {
+	if s.bounds == nil {
+		return nil
+	}
+
+	inBounds := func(idx Index) bool {
+		return s.idxCmp(idx, s.bounds.PeriodStart) >= 0 && s.idxCmp(idx, s.bounds.PeriodEnd) <= 0
+	}
+
+	chunks := make([]fileDataChunk[Index], 0, len(s.chunks))
+	trimmed := false
+
+	for _, chunk := range s.chunks {
+		if s.idxCmp(chunk.last, s.bounds.PeriodStart) < 0 || s.idxCmp(chunk.first, s.bounds.PeriodEnd) > 0 {
+			trimmed = true
+			continue
+		}
+
+		if !inBounds(chunk.first) || !inBounds(chunk.last) {
+			var err error
+			if chunk, err = s.trimChunk(chunk, inBounds); err != nil {
+				return err
+			}
+
+			trimmed = true
+		}
+
+		chunks = append(chunks, chunk)
+	}
+
+	if !trimmed {
+		return nil
+	}
+
+	s.chunks = chunks
+
+	return s.Compact()
+}
+
+// Replaces content of the series with segments stored in log files of dir, which were created by NewFileDataFactory.
+// Bounds of each segment are read from its log, so series can be restored after any modification.
+// Modification, which was interrupted by crash, may be restored partially. Use WAL to restore such modifications entirely.
+// Logs left by interrupted modifications (e.g. of segments, which were merged into other segment) are removed.
+// Data factory of the series must be NewFileDataFactory with the same dir.
+func (s *Series[Data, Index]) RestoreFileDataDir(dir string, codec Codec[Data, Index], opts FileDataOptions) error {
+	paths, err := filepath.Glob(filepath.Join(dir, fileDataLogPattern))
+	if err != nil {
+		return errors.Wrapf(err, "failed to list log files in %v", dir)
+	}
+
+	storages := make([]*FileData[Data, Index], 0, len(paths))
+
+	for _, path := range paths {
+		storage, err := OpenFileData(path, s.getIdx, s.idxCmp, codec, opts)
+		if err != nil {
+			return err
+		}
+
+		// Creation of the storage was interrupted before it got any segment
+		if storage.bounds == nil {
+			if err := storage.Remove(); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		if err := storage.trimToBounds(); err != nil {
+			return err
+		}
+
+		storages = append(storages, storage)
+	}
+
+	slices.SortFunc(storages, func(a, b *FileData[Data, Index]) int {
+		if c := s.idxCmp(a.bounds.PeriodStart, b.bounds.PeriodStart); c != 0 {
+			return c
+		}
+
+		return s.idxCmp(b.bounds.PeriodEnd, a.bounds.PeriodEnd)
+	})
+
+	state := &SeriesState[Data, Index]{Segments: make([]*SeriesSegmentFields[Data, Index], 0, len(storages))}
+
+	for _, storage := range storages {
+		if len(state.Segments) != 0 {
+			prev := state.Segments[len(state.Segments)-1]
+
+			if s.idxCmp(storage.bounds.PeriodStart, prev.PeriodEnd) <= 0 {
+				if s.idxCmp(storage.bounds.PeriodEnd, prev.PeriodEnd) > 0 {
+					return errors.Errorf("segment [ %v ; %v ] of log %v overlaps with segment [ %v ; %v ]",
+						storage.bounds.PeriodStart, storage.bounds.PeriodEnd, storage.Path(), prev.PeriodStart, prev.PeriodEnd)
+				}
+
+				// Segment was merged into the containing one, but its log was not removed yet
+				if err := storage.Remove(); err != nil {
+					return err
+				}
+
+				continue
+			}
+		}
+
+		state.Segments = append(state.Segments, &SeriesSegmentFields[Data, Index]{
+			PeriodBounds: *storage.bounds,
+			Data:         storage,
+			Empty:        len(storage.chunks) == 0,
+		})
+	}
+
+	return s.Restore(state)
+}
@@ -0,0 +1,339 @@
+package sparse_test
+
+import (
+	"cmp"
+	"math"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/nnikolash/go-sparse"
+	"github.com/nnikolash/go-sparse/sparsetest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileData_Conformance(t *testing.T) {
+	t.Parallel()
+
+	for name, opts := range map[string]sparse.FileDataOptions{
+		"default":        {},
+		"compact always": {CompactAfter: 1, ChunkSize: 2},
+		"compact never":  {CompactAfter: -1},
+		"sync":           {Sync: true},
+	} {
+		t.Run(name, func(t *testing.T) {
+			sparsetest.RunSeriesDataConformance(t, sparse.NewFileDataFactory(t.TempDir(), sparse.JSONCodec[sparsetest.Item, int]{}, opts), sparsetest.ItemFixture())
+		})
+	}
+}
+
+func openIntFileData(t *testing.T, path string, opts sparse.FileDataOptions) *sparse.FileData[int, int] {
+	s, err := sparse.OpenFileData(path, func(data *int) int { return *data }, func(idx1, idx2 int) int { return idx1 - idx2 }, sparse.JSONCodec[int, int]{}, opts)
+	require.NoError(t, err)
+	return s
+}
+
+func newIntFileData(t *testing.T, dir string, opts sparse.FileDataOptions, data ...int) *sparse.FileData[int, int] {
+	factory := sparse.NewFileDataFactory(dir, sparse.JSONCodec[int, int]{}, opts)
+	s, err := factory(func(data *int) int { return *data }, func(idx1, idx2 int) int { return idx1 - idx2 }, 0, 0, data)
+	require.NoError(t, err)
+	return s.(*sparse.FileData[int, int])
+}
+
+func TestFileData_Reopen(t *testing.T) {
+	t.Parallel()
+
+	opts := sparse.FileDataOptions{CompactAfter: -1}
+	s := newIntFileData(t, t.TempDir(), opts, 10, 20, 30, 40, 50)
+
+	require.NoError(t, s.Merge([]int{25, 30, 35}))
+	require.NoError(t, s.Delete(45, 60))
+	require.NoError(t, s.Merge([]int{60, 70}))
+	require.NoError(t, s.Delete(10, 10))
+
+	expected := []int{20, 25, 30, 35, 40, 60, 70}
+	require.Equal(t, expected, must2(s.Get(0, 100)))
+
+	reopened := openIntFileData(t, s.Path(), opts)
+	require.Equal(t, expected, must2(reopened.Get(0, 100)))
+
+	require.NoError(t, reopened.Compact())
+	require.Equal(t, expected, must2(reopened.Get(0, 100)))
+
+	reopened = openIntFileData(t, s.Path(), opts)
+	require.Equal(t, expected, must2(reopened.Get(0, 100)))
+	require.Equal(t, []int{30, 35}, must2(reopened.Get(26, 39)))
+}
+
+func TestFileData_RecoverTornWrite(t *testing.T) {
+	t.Parallel()
+
+	opts := sparse.FileDataOptions{CompactAfter: -1}
+	s := newIntFileData(t, t.TempDir(), opts, 10, 20)
+	require.NoError(t, s.Merge([]int{30, 40}))
+
+	stat, err := os.Stat(s.Path())
+	require.NoError(t, err)
+	validSize := stat.Size()
+
+	require.NoError(t, s.Merge([]int{50, 60}))
+
+	// Last record is written partially
+	require.NoError(t, os.Truncate(s.Path(), validSize+5))
+
+	reopened := openIntFileData(t, s.Path(), opts)
+	require.Equal(t, []int{10, 20, 30, 40}, must2(reopened.Get(0, 100)))
+
+	stat, err = os.Stat(s.Path())
+	require.NoError(t, err)
+	require.Equal(t, validSize, stat.Size())
+
+	// Writing after recovery continues valid log
+	require.NoError(t, reopened.Merge([]int{70}))
+	reopened = openIntFileData(t, s.Path(), opts)
+	require.Equal(t, []int{10, 20, 30, 40, 70}, must2(reopened.Get(0, 100)))
+}
+
+func TestFileData_RecoverCorruptedRecord(t *testing.T) {
+	t.Parallel()
+
+	opts := sparse.FileDataOptions{CompactAfter: -1}
+	s := newIntFileData(t, t.TempDir(), opts, 10, 20)
+
+	stat, err := os.Stat(s.Path())
+	require.NoError(t, err)
+	validSize := stat.Size()
+
+	require.NoError(t, s.Merge([]int{30, 40}))
+
+	b, err := os.ReadFile(s.Path())
+	require.NoError(t, err)
+	b[len(b)-2] ^= 0xFF
+	require.NoError(t, os.WriteFile(s.Path(), b, 0o644))
+
+	reopened := openIntFileData(t, s.Path(), opts)
+	require.Equal(t, []int{10, 20}, must2(reopened.Get(0, 100)))
+
+	stat, err = os.Stat(s.Path())
+	require.NoError(t, err)
+	require.Equal(t, validSize, stat.Size())
+}
+
+func TestFileData_AutoCompact(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	compacted := newIntFileData(t, dir, sparse.FileDataOptions{CompactAfter: 10})
+	notCompacted := newIntFileData(t, dir, sparse.FileDataOptions{CompactAfter: -1})
+
+	for i := 0; i < 100; i++ {
+		require.NoError(t, compacted.Merge([]int{i % 5, 10}))
+		require.NoError(t, notCompacted.Merge([]int{i % 5, 10}))
+	}
+
+	compactedStat, err := os.Stat(compacted.Path())
+	require.NoError(t, err)
+	notCompactedStat, err := os.Stat(notCompacted.Path())
+	require.NoError(t, err)
+	require.Less(t, compactedStat.Size()*5, notCompactedStat.Size())
+
+	require.Equal(t, []int{0, 1, 2, 3, 4, 10}, must2(compacted.Get(0, 100)))
+	require.Equal(t, []int{0, 1, 2, 3, 4, 10}, must2(openIntFileData(t, compacted.Path(), sparse.FileDataOptions{}).Get(0, 100)))
+}
+
+func TestFileData_Series(t *testing.T) {
+	t.Parallel()
+
+	series := sparse.NewSeries(
+		sparse.NewFileDataFactory(t.TempDir(), sparse.JSONCodec[int, int]{}, sparse.FileDataOptions{}),
+		func(data *int) int { return *data },
+		func(idx1, idx2 int) int { return idx1 - idx2 },
+		nil,
+	)
+	series.SetValidateOnChange(true)
+
+	require.NoError(t, series.AddPeriod(10, 20, []int{10, 15, 20}))
+	require.NoError(t, series.AddPeriod(30, 40, []int{35}))
+	require.NoError(t, series.AddPeriod(15, 35, []int{18, 25}))
+	require.NoError(t, series.DeletePeriod(0, 12))
+
+	require.Equal(t, []int{18, 25}, must2(series.Get(18, 40)))
+
+	require.NoError(t, series.AddPeriod(38, 38, []int{38}))
+	require.Equal(t, []int{18, 25, 38}, must2(series.Get(18, 40)))
+}
+
+func newIntFileDataSeries(dir string) *sparse.Series[int, int] {
+	series := sparse.NewSeries(
+		sparse.NewFileDataFactory(dir, sparse.JSONCodec[int, int]{}, sparse.FileDataOptions{}),
+		func(data *int) int { return *data },
+		func(idx1, idx2 int) int { return idx1 - idx2 },
+		nil,
+	)
+	series.SetValidateOnChange(true)
+
+	return series
+}
+
+func TestFileData_RestoreSeriesFromDir(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+
+	series := newIntFileDataSeries(dir)
+	require.NoError(t, series.AddPeriod(10, 20, []int{10, 15, 20}))
+	require.NoError(t, series.AddPeriod(18, 30, []int{25, 30}))
+	require.NoError(t, series.AddPeriod(40, 50, []int{45}))
+	require.NoError(t, series.AddPeriod(60, 70, nil))
+	require.NoError(t, series.DeletePeriod(12, 14))
+	require.NoError(t, series.AddPeriod(35, 42, []int{36}))
+	require.Equal(t, []sparse.PeriodBounds[int]{{PeriodStart: 10, PeriodEnd: 10}, {PeriodStart: 15, PeriodEnd: 30}, {PeriodStart: 35, PeriodEnd: 50}, {PeriodStart: 60, PeriodEnd: 70}}, boundsOf(series.Segments()))
+
+	// Logs of merged segments are removed
+	require.NoError(t, series.AddPeriod(28, 62, []int{29, 61}))
+	require.Equal(t, []sparse.PeriodBounds[int]{{PeriodStart: 10, PeriodEnd: 10}, {PeriodStart: 15, PeriodEnd: 70}}, boundsOf(series.Segments()))
+	require.Equal(t, 2, countFiles(t, dir))
+
+	restored := newIntFileDataSeries(dir)
+	require.NoError(t, restored.RestoreFileDataDir(dir, sparse.JSONCodec[int, int]{}, sparse.FileDataOptions{}))
+	requireSameSeries(t, series, restored)
+
+	// Logs of deleted segments are removed
+	require.NoError(t, restored.DeletePeriod(0, 20))
+	require.NoError(t, restored.AddPeriod(80, 90, nil))
+	require.Equal(t, 2, countFiles(t, dir))
+
+	restoredAgain := newIntFileDataSeries(dir)
+	require.NoError(t, restoredAgain.RestoreFileDataDir(dir, sparse.JSONCodec[int, int]{}, sparse.FileDataOptions{}))
+	requireSameSeries(t, restored, restoredAgain)
+	require.Equal(t, []sparse.PeriodBounds[int]{{PeriodStart: 25, PeriodEnd: 70}, {PeriodStart: 80, PeriodEnd: 90}}, boundsOf(restoredAgain.Segments()))
+	require.True(t, restoredAgain.GetPeriod(80, 90).Empty)
+	require.Equal(t, []int{25, 29, 61}, must2(restoredAgain.Get(25, 70)))
+}
+
+func TestFileData_RestoreSeriesFromDirAfterInterruptedChanges(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	factory := sparse.NewFileDataFactory(dir, sparse.JSONCodec[int, int]{}, sparse.FileDataOptions{})
+	getIdx := func(data *int) int { return *data }
+	idxCmp := func(idx1, idx2 int) int { return idx1 - idx2 }
+
+	series := newIntFileDataSeries(dir)
+	require.NoError(t, series.AddPeriod(10, 30, []int{10, 20, 30}))
+	require.NoError(t, series.AddPeriod(40, 50, []int{45}))
+
+	// Log of segment, which was merged into other segment
+	must2(factory(getIdx, idxCmp, 12, 18, []int{15}))
+	// Log, which got no bounds
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "segment-interrupted.log"), nil, 0o644))
+	// Data merged into segment, which was not extended
+	storage := series.GetPeriod(40, 50).Data.(*sparse.FileData[int, int])
+	require.NoError(t, openIntFileData(t, storage.Path(), sparse.FileDataOptions{}).Merge([]int{55}))
+	require.Equal(t, 4, countFiles(t, dir))
+
+	restored := newIntFileDataSeries(dir)
+	require.NoError(t, restored.RestoreFileDataDir(dir, sparse.JSONCodec[int, int]{}, sparse.FileDataOptions{}))
+	requireSameSeries(t, series, restored)
+	require.Equal(t, 2, countFiles(t, dir))
+
+	// Partially overlapping segments can't be restored
+	must2(factory(getIdx, idxCmp, 25, 35, []int{35}))
+	require.Error(t, newIntFileDataSeries(dir).RestoreFileDataDir(dir, sparse.JSONCodec[int, int]{}, sparse.FileDataOptions{}))
+}
+
+func TestFileData_RestoreSeriesFromEmptyDir(t *testing.T) {
+	t.Parallel()
+
+	series := intSparseSeries()
+	require.NoError(t, series.AddPeriod(10, 20, []int{15}))
+
+	require.NoError(t, series.RestoreFileDataDir(t.TempDir(), sparse.JSONCodec[int, int]{}, sparse.FileDataOptions{}))
+	require.Empty(t, series.Segments())
+}
+
+func TestFileData_FactoryRemovesLogOnError(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	factory := sparse.NewFileDataFactory(dir, sparse.JSONCodec[float64, float64]{}, sparse.FileDataOptions{})
+
+	_, err := factory(func(data *float64) float64 { return *data }, func(idx1, idx2 float64) int { return cmp.Compare(idx1, idx2) }, 0, 1, []float64{math.NaN()})
+	require.Error(t, err)
+	require.Equal(t, 0, countFiles(t, dir))
+}
+
+func TestFileData_MatchesArrayData(t *testing.T) {
+	t.Parallel()
+
+	getIdx := func(data *int) int { return *data }
+	idxCmp := func(idx1, idx2 int) int { return idx1 - idx2 }
+
+	for _, opts := range []sparse.FileDataOptions{{CompactAfter: -1}, {CompactAfter: 3, ChunkSize: 3}} {
+		fileData := newIntFileData(t, t.TempDir(), opts)
+		arrayData := must2(sparse.NewArrayData(getIdx, idxCmp, 0, 0, nil))
+
+		for i := 0; i < 500; i++ {
+			start := rand.IntN(100)
+			end := start + rand.IntN(20)
+
+			if rand.IntN(3) == 0 {
+				require.NoError(t, fileData.Delete(start, end))
+				require.NoError(t, arrayData.Delete(start, end))
+			} else {
+				var data []int
+				for idx := start; idx <= end; idx++ {
+					if idx == start || idx == end || rand.IntN(2) == 0 {
+						data = append(data, idx)
+					}
+				}
+
+				require.NoError(t, fileData.Merge(data))
+				require.NoError(t, arrayData.Merge(data))
+			}
+
+			require.Equal(t, must2(arrayData.Get(0, 200)), must2(fileData.Get(0, 200)), "step %v", i)
+		}
+
+		reopened := openIntFileData(t, fileData.Path(), opts)
+		require.Equal(t, must2(arrayData.Get(0, 200)), must2(reopened.Get(0, 200)))
+	}
+}
+
+func TestFileData_ConcurrentReads(t *testing.T) {
+	t.Parallel()
+
+	series := sparse.NewConcurrentSeries(
+		sparse.NewFileDataFactory(t.TempDir(), sparse.JSONCodec[int, int]{}, sparse.FileDataOptions{CompactAfter: -1}),
+		func(data *int) int { return *data },
+		func(idx1, idx2 int) int { return idx1 - idx2 },
+		nil,
+	)
+
+	// Each addition is stored in separate record, so reads switch between cached records
+	require.NoError(t, series.AddPeriod(0, 100, []int{0, 50, 100}))
+	require.NoError(t, series.AddPeriod(20, 30, []int{25}))
+	require.NoError(t, series.AddPeriod(70, 80, []int{75}))
+
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for i := 0; i < 100; i++ {
+				res, err := series.Get(0, 100)
+				assert.NoError(t, err)
+				assert.Equal(t, []int{0, 25, 50, 75, 100}, res)
+
+				_, err = series.Snapshot()
+				assert.NoError(t, err)
+			}
+		}()
+	}
+
+	wg.Wait()
+}
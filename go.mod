@@ -1,8 +1,6 @@
 module github.com/nnikolash/go-sparse
 
-go 1.22.0
-
-toolchain go1.22.5
+go 1.23.0
 
 require (
 	github.com/pkg/errors v0.9.1
@@ -0,0 +1,286 @@
+package sparse
+
+import (
+	"math"
+	"math/bits"
+
+	"github.com/pkg/errors"
+)
+
+// Writes values bit by bit, starting from the most significant bit.
+type bitWriter struct {
+	b     []byte
+	count uint8 // Number of free bits in the last byte
+}
+
+func (w *bitWriter) writeBit(bit bool) {
+	if w.count == 0 {
+		w.b = append(w.b, 0)
+		w.count = 8
+	}
+
+	w.count--
+
+	if bit {
+		w.b[len(w.b)-1] |= 1 << w.count
+	}
+}
+
+func (w *bitWriter) writeBits(v uint64, n int) {
+	for i := n - 1; i >= 0; i-- {
+		w.writeBit(v&(1<<i) != 0)
+	}
+}
+
+type bitReader struct {
+	b   []byte
+	pos int // Number of read bits
+}
+
+func (r *bitReader) readBit() (bool, error) {
+	if r.pos >= 8*len(r.b) {
+		return false, errors.New("unexpected end of compressed block")
+	}
+
+	bit := r.b[r.pos/8]&(1<<(7-r.pos%8)) != 0
+	r.pos++
+
+	return bit, nil
+}
+
+func (r *bitReader) readBits(n int) (uint64, error) {
+	var v uint64
+
+	for i := 0; i < n; i++ {
+		bit, err := r.readBit()
+		if err != nil {
+			return 0, err
+		}
+
+		v <<= 1
+		if bit {
+			v |= 1
+		}
+	}
+
+	return v, nil
+}
+
+// Ranges of delta-of-delta, which are encoded with fewer bits. Each next bucket has one more control bit.
+var gorillaDodBuckets = []struct {
+	bits int
+	min  int64
+	max  int64
+}{
+	{bits: 7, min: -63, max: 64},
+	{bits: 9, min: -255, max: 256},
+	{bits: 12, min: -2047, max: 2048},
+}
+
+// Encodes pairs of timestamp and value in the style of Gorilla: timestamps are stored as delta-of-delta
+// and values as XOR with the previous value.
+type gorillaEncoder struct {
+	w bitWriter
+	n int
+
+	prevTs       int64
+	prevDelta    int64
+	prevValue    uint64
+	prevLeading  int
+	prevTrailing int
+}
+
+func (e *gorillaEncoder) encode(ts int64, value float64) {
+	v := math.Float64bits(value)
+
+	if e.n == 0 {
+		e.w.writeBits(uint64(ts), 64)
+		e.w.writeBits(v, 64)
+		e.prevTs, e.prevValue = ts, v
+		e.prevLeading = -1
+		e.n++
+		return
+	}
+
+	delta := ts - e.prevTs
+	e.encodeDod(delta - e.prevDelta)
+	e.prevTs, e.prevDelta = ts, delta
+
+	e.encodeXor(v ^ e.prevValue)
+	e.prevValue = v
+
+	e.n++
+}
+
+func (e *gorillaEncoder) encodeDod(dod int64) {
+	if dod == 0 {
+		e.w.writeBit(false)
+		return
+	}
+
+	for _, bucket := range gorillaDodBuckets {
+		e.w.writeBit(true)
+
+		if dod >= bucket.min && dod <= bucket.max {
+			e.w.writeBit(false)
+			e.w.writeBits(uint64(dod-bucket.min), bucket.bits)
+			return
+		}
+	}
+
+	e.w.writeBit(true)
+	e.w.writeBits(uint64(dod), 64)
+}
+
+func (e *gorillaEncoder) encodeXor(xor uint64) {
+	if xor == 0 {
+		e.w.writeBit(false)
+		return
+	}
+
+	e.w.writeBit(true)
+
+	leading := min(bits.LeadingZeros64(xor), 31)
+	trailing := bits.TrailingZeros64(xor)
+
+	if e.prevLeading != -1 && leading >= e.prevLeading && trailing >= e.prevTrailing {
+		e.w.writeBit(false)
+		e.w.writeBits(xor>>e.prevTrailing, 64-e.prevLeading-e.prevTrailing)
+		return
+	}
+
+	e.w.writeBit(true)
+
+	meaningful := 64 - leading - trailing
+	e.w.writeBits(uint64(leading), 5)
+	// Length of 64 does not fit into 6 bits, but zero length is impossible, so it is used instead
+	e.w.writeBits(uint64(meaningful%64), 6)
+	e.w.writeBits(xor>>trailing, meaningful)
+
+	e.prevLeading, e.prevTrailing = leading, trailing
+}
+
+type gorillaDecoder struct {
+	r bitReader
+	n int
+
+	ts           int64
+	delta        int64
+	value        uint64
+	prevLeading  int
+	prevTrailing int
+}
+
+func (d *gorillaDecoder) decode() (ts int64, value float64, _ error) {
+	if d.n == 0 {
+		rawTs, err := d.r.readBits(64)
+		if err != nil {
+			return 0, 0, err
+		}
+
+		if d.value, err = d.r.readBits(64); err != nil {
+			return 0, 0, err
+		}
+
+		d.ts = int64(rawTs)
+		d.prevLeading = -1
+		d.n++
+
+		return d.ts, math.Float64frombits(d.value), nil
+	}
+
+	dod, err := d.decodeDod()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	d.delta += dod
+	d.ts += d.delta
+
+	xor, err := d.decodeXor()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	d.value ^= xor
+	d.n++
+
+	return d.ts, math.Float64frombits(d.value), nil
+}
+
+func (d *gorillaDecoder) decodeDod() (int64, error) {
+	bit, err := d.r.readBit()
+	if err != nil || !bit {
+		return 0, err
+	}
+
+	for _, bucket := range gorillaDodBuckets {
+		if bit, err = d.r.readBit(); err != nil {
+			return 0, err
+		}
+
+		if !bit {
+			v, err := d.r.readBits(bucket.bits)
+			if err != nil {
+				return 0, err
+			}
+
+			return int64(v) + bucket.min, nil
+		}
+	}
+
+	v, err := d.r.readBits(64)
+
+	return int64(v), err
+}
+
+func (d *gorillaDecoder) decodeXor() (uint64, error) {
+	bit, err := d.r.readBit()
+	if err != nil || !bit {
+		return 0, err
+	}
+
+	if bit, err = d.r.readBit(); err != nil {
+		return 0, err
+	}
+
+	if !bit {
+		if d.prevLeading == -1 {
+			return 0, errors.New("invalid compressed block: reuse of missing XOR window")
+		}
+
+		v, err := d.r.readBits(64 - d.prevLeading - d.prevTrailing)
+		if err != nil {
+			return 0, err
+		}
+
+		return v << d.prevTrailing, nil
+	}
+
+	leading, err := d.r.readBits(5)
+	if err != nil {
+		return 0, err
+	}
+
+	meaningful, err := d.r.readBits(6)
+	if err != nil {
+		return 0, err
+	}
+	if meaningful == 0 {
+		meaningful = 64
+	}
+
+	if int(leading)+int(meaningful) > 64 {
+		return 0, errors.Errorf("invalid compressed block: XOR window %v + %v", leading, meaningful)
+	}
+
+	v, err := d.r.readBits(int(meaningful))
+	if err != nil {
+		return 0, err
+	}
+
+	d.prevLeading = int(leading)
+	d.prevTrailing = 64 - int(leading) - int(meaningful)
+
+	return v << d.prevTrailing, nil
+}
@@ -0,0 +1,95 @@
+package sparse
+
+import (
+	"iter"
+)
+
+// Returns sequence of data of the period [ periodStart ; periodEnd ] in ascending order of index.
+// Period must be fully covered by the series.
+func (s *Series[Data, Index]) All(periodStart, periodEnd Index) (iter.Seq2[Index, Data], error) {
+	return s.iterate(periodStart, periodEnd, false)
+}
+
+// Same as All, but in descending order of index.
+func (s *Series[Data, Index]) Backward(periodStart, periodEnd Index) (iter.Seq2[Index, Data], error) {
+	return s.iterate(periodStart, periodEnd, true)
+}
+
+func (s *Series[Data, Index]) iterate(periodStart, periodEnd Index, backward bool) (iter.Seq2[Index, Data], error) {
+	segment, err := s.getCoveringSegment(periodStart, periodEnd)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := iterateData(segment.Data, periodStart, periodEnd, backward)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(yield func(Index, Data) bool) {
+		for elem := range data {
+			if !yield(s.getIdx(&elem), elem) {
+				return
+			}
+		}
+	}, nil
+}
+
+func iterateData[Data any, Index any](storage SeriesData[Data, Index], periodStart, periodEnd Index, backward bool) (iter.Seq[Data], error) {
+	if iterable, ok := storage.(IterableSeriesData[Data, Index]); ok {
+		if backward {
+			return iterable.Backward(periodStart, periodEnd)
+		}
+		return iterable.All(periodStart, periodEnd)
+	}
+
+	data, err := storage.Get(periodStart, periodEnd)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(yield func(Data) bool) {
+		if backward {
+			for i := len(data) - 1; i >= 0; i-- {
+				if !yield(data[i]) {
+					return
+				}
+			}
+			return
+		}
+
+		for i := range data {
+			if !yield(data[i]) {
+				return
+			}
+		}
+	}, nil
+}
+
+// Returns sequence of segments, which intersect with the period [ periodStart ; periodEnd ].
+// If nonEmptyOnly is set, empty segments are skipped without visiting them.
+// Series must not be modified during iteration.
+func (s *Series[Data, Index]) SegmentsBetween(periodStart, periodEnd Index, nonEmptyOnly bool) iter.Seq[*SeriesSegment[Data, Index]] {
+	return func(yield func(*SeriesSegment[Data, Index]) bool) {
+		if s.idxCmp(periodStart, periodEnd) > 0 {
+			return
+		}
+
+		endsAfterStart := func(segment *SeriesSegment[Data, Index]) bool {
+			return s.idxCmp(segment.PeriodEnd, periodStart) >= 0
+		}
+
+		var segments iter.Seq[*SeriesSegment[Data, Index]]
+		if nonEmptyOnly {
+			segments = s.segments.NonEmptyValues(s.segments.SearchNonEmpty(endsAfterStart))
+		} else {
+			segments = s.segments.Values(s.segments.Search(endsAfterStart))
+		}
+
+		for segment := range segments {
+			if s.idxCmp(segment.PeriodStart, periodEnd) > 0 || !yield(segment) {
+				return
+			}
+		}
+	}
+}
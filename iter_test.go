@@ -0,0 +1,169 @@
+package sparse_test
+
+import (
+	"testing"
+
+	"github.com/nnikolash/go-sparse"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSparseSeries_All(t *testing.T) {
+	t.Parallel()
+
+	type Elem struct {
+		Key int
+		Val string
+	}
+	series := sparse.NewSeries[Elem, int](
+		sparse.NewArrayData,
+		func(data *Elem) int { return data.Key },
+		func(idx1, idx2 int) int { return idx1 - idx2 },
+		nil,
+	)
+
+	err := series.AddData([]Elem{{10, "a"}, {20, "b"}, {30, "c"}, {40, "d"}})
+	require.NoError(t, err)
+	err = series.AddData([]Elem{{60, "e"}})
+	require.NoError(t, err)
+
+	seq, err := series.All(15, 40)
+	require.NoError(t, err)
+
+	var keys []int
+	var vals []string
+	for idx, elem := range seq {
+		keys = append(keys, idx)
+		vals = append(vals, elem.Val)
+	}
+	require.Equal(t, []int{20, 30, 40}, keys)
+	require.Equal(t, []string{"b", "c", "d"}, vals)
+
+	seq, err = series.Backward(10, 35)
+	require.NoError(t, err)
+
+	keys = nil
+	for idx := range seq {
+		keys = append(keys, idx)
+	}
+	require.Equal(t, []int{30, 20, 10}, keys)
+
+	keys = nil
+	for idx := range seq {
+		keys = append(keys, idx)
+		if idx == 20 {
+			break
+		}
+	}
+	require.Equal(t, []int{30, 20}, keys)
+
+	seq, err = series.All(31, 39)
+	require.NoError(t, err)
+	for range seq {
+		require.Fail(t, "no data expected")
+	}
+
+	_, err = series.All(30, 60)
+	var missingErr *sparse.MissingPeriodError[int]
+	require.ErrorAs(t, err, &missingErr)
+	require.Equal(t, 40, missingErr.PeriodStart)
+	require.Equal(t, 60, missingErr.PeriodEnd)
+
+	_, err = series.Backward(0, 5)
+	require.Error(t, err)
+}
+
+func TestSparseSeries_AllNotIterableData(t *testing.T) {
+	t.Parallel()
+
+	series := sparse.NewSeries[int, int](
+		func(getIdx func(data *int) int, idxCmp func(idx1, idx2 int) int, periodStart, periodEnd int, data []int) (sparse.SeriesData[int, int], error) {
+			storage, err := sparse.NewArrayData(getIdx, idxCmp, periodStart, periodEnd, data)
+			return notSnapshotableData[int, int]{storage}, err
+		},
+		func(data *int) int { return *data },
+		func(idx1, idx2 int) int { return idx1 - idx2 },
+		nil,
+	)
+
+	err := series.AddData([]int{10, 20, 30})
+	require.NoError(t, err)
+
+	seq, err := series.All(10, 30)
+	require.NoError(t, err)
+
+	var keys []int
+	for idx := range seq {
+		keys = append(keys, idx)
+	}
+	require.Equal(t, []int{10, 20, 30}, keys)
+
+	seq, err = series.Backward(10, 30)
+	require.NoError(t, err)
+
+	keys = nil
+	for idx := range seq {
+		keys = append(keys, idx)
+	}
+	require.Equal(t, []int{30, 20, 10}, keys)
+}
+
+func TestSparseSeries_SegmentsBetween(t *testing.T) {
+	t.Parallel()
+
+	series := intSparseSeries()
+
+	for i := 0; i < 1000; i++ {
+		err := series.AddPeriod(i*10, i*10+5, nil)
+		require.NoError(t, err)
+	}
+	err := series.AddData([]int{3000, 3005})
+	require.NoError(t, err)
+	err = series.AddData([]int{7000, 7001})
+	require.NoError(t, err)
+
+	var bounds []sparse.PeriodBounds[int]
+	for segment := range series.SegmentsBetween(2995, 3025, false) {
+		bounds = append(bounds, segment.PeriodBounds)
+	}
+	require.Equal(t, []sparse.PeriodBounds[int]{
+		{PeriodStart: 2990, PeriodEnd: 2995},
+		{PeriodStart: 3000, PeriodEnd: 3005},
+		{PeriodStart: 3010, PeriodEnd: 3015},
+		{PeriodStart: 3020, PeriodEnd: 3025},
+	}, bounds)
+
+	bounds = nil
+	for segment := range series.SegmentsBetween(0, 10000, true) {
+		bounds = append(bounds, segment.PeriodBounds)
+	}
+	require.Equal(t, []sparse.PeriodBounds[int]{
+		{PeriodStart: 3000, PeriodEnd: 3005},
+		{PeriodStart: 7000, PeriodEnd: 7005},
+	}, bounds)
+
+	bounds = nil
+	for segment := range series.SegmentsBetween(3006, 7000, true) {
+		bounds = append(bounds, segment.PeriodBounds)
+		break
+	}
+	require.Equal(t, []sparse.PeriodBounds[int]{{PeriodStart: 7000, PeriodEnd: 7005}}, bounds)
+
+	for range series.SegmentsBetween(3006, 6999, true) {
+		require.Fail(t, "no segments expected")
+	}
+	for range series.SegmentsBetween(10, 5, false) {
+		require.Fail(t, "no segments expected")
+	}
+
+	p := series.GetPeriodClosestFromStart(6999, true)
+	require.Equal(t, 3000, p.PeriodStart)
+	p = series.GetPeriodClosestFromEnd(3006, true)
+	require.Equal(t, 7000, p.PeriodStart)
+	p = series.GetPeriodClosestFromEnd(7006, true)
+	require.Nil(t, p)
+
+	err = series.DeletePeriod(3000, 3005)
+	require.NoError(t, err)
+	p = series.GetPeriodClosestFromStart(6999, true)
+	require.Nil(t, p)
+}
@@ -0,0 +1,218 @@
+package sparse
+
+import (
+	"slices"
+
+	"github.com/pkg/errors"
+)
+
+// Changes made by single modification of the series, which are undone, if the modification fails.
+type seriesJournal[Data any, Index any] struct {
+	// Length of queue of removed storages before the modification.
+	removedLen int
+	replaces   []segmentTreeReplace[Data, Index]
+	// Original state of segments, which existed before the modification.
+	backups map[*SeriesSegment[Data, Index]]*segmentBackup[Data, Index]
+	// Segments created by the modification.
+	created map[*SeriesSegment[Data, Index]]struct{}
+}
+
+type segmentTreeReplace[Data any, Index any] struct {
+	from     int
+	removed  []*SeriesSegment[Data, Index]
+	inserted int
+}
+
+type segmentBackup[Data any, Index any] struct {
+	fields SeriesSegmentFields[Data, Index]
+	tiered bool
+	cold   bool
+	// Original data of changed periods of storages in order of changes.
+	changes []storageChange[Data, Index]
+}
+
+type storageChange[Data any, Index any] struct {
+	storage     SeriesData[Data, Index]
+	periodStart Index
+	periodEnd   Index
+	data        []Data
+}
+
+// Applies modification of the series. If it fails, changes of segments and their storages are undone.
+// Storages of removed segments are released only after successful modification.
+func (s *Series[Data, Index]) modify(apply func() error) error {
+	s.journal = &seriesJournal[Data, Index]{
+		removedLen: len(s.removed),
+		backups:    map[*SeriesSegment[Data, Index]]*segmentBackup[Data, Index]{},
+		created:    map[*SeriesSegment[Data, Index]]struct{}{},
+	}
+
+	err := apply()
+
+	journal := s.journal
+	s.journal = nil
+
+	if err != nil {
+		if undoErr := s.undo(journal); undoErr != nil {
+			return errors.Wrapf(err, "failed to undo changes: %v", undoErr)
+		}
+
+		return err
+	}
+
+	return s.releaseRemovedStorages()
+}
+
+// Saves original state of the segment before it is changed or removed.
+func (s *Series[Data, Index]) backupSegment(segment *SeriesSegment[Data, Index]) *segmentBackup[Data, Index] {
+	if s.journal == nil || segment.Data == nil {
+		return nil
+	}
+	if _, ok := s.journal.created[segment]; ok {
+		return nil
+	}
+	if backup, ok := s.journal.backups[segment]; ok {
+		return backup
+	}
+
+	backup := &segmentBackup[Data, Index]{fields: segment.SeriesSegmentFields}
+	if s.tiering != nil {
+		_, backup.cold = s.tiering.cold[segment]
+		_, hot := s.tiering.hotElems[segment]
+		backup.tiered = hot || backup.cold
+	}
+
+	s.journal.backups[segment] = backup
+
+	return backup
+}
+
+// Saves original data of the period of segment storage before it is changed.
+func (s *Series[Data, Index]) backupSegmentData(segment *SeriesSegment[Data, Index], periodStart, periodEnd Index) error {
+	backup := s.backupSegment(segment)
+	if backup == nil {
+		return nil
+	}
+
+	data, err := segment.Data.Get(periodStart, periodEnd)
+	if err != nil {
+		return err
+	}
+
+	backup.changes = append(backup.changes, storageChange[Data, Index]{
+		storage:     segment.Data,
+		periodStart: periodStart,
+		periodEnd:   periodEnd,
+		data:        slices.Clone(data),
+	})
+
+	return nil
+}
+
+// Called by segment tree for every Replace.
+func (s *Series[Data, Index]) segmentsReplaced(from int, removed, inserted []*SeriesSegment[Data, Index]) {
+	if s.journal != nil {
+		s.journal.replaces = append(s.journal.replaces, segmentTreeReplace[Data, Index]{from: from, removed: removed, inserted: len(inserted)})
+
+		for _, segment := range removed {
+			s.backupSegment(segment)
+		}
+		for _, segment := range inserted {
+			if _, ok := s.journal.backups[segment]; !ok {
+				s.journal.created[segment] = struct{}{}
+			}
+		}
+	}
+
+	for _, segment := range removed {
+		if !slices.Contains(inserted, segment) {
+			s.segmentRemoved(segment)
+		}
+	}
+}
+
+// Returns segments and their storages into the state before failed modification and releases storages created by it.
+func (s *Series[Data, Index]) undo(journal *seriesJournal[Data, Index]) error {
+	released := slices.Clone(s.removed[journal.removedLen:])
+	s.removed = s.removed[:journal.removedLen]
+
+	for segment := range journal.created {
+		if s.tiering != nil {
+			s.tiering.remove(segment)
+		}
+
+		released = append(released, segment.Data)
+	}
+
+	original := make([]SeriesData[Data, Index], 0, len(journal.backups))
+
+	for segment, backup := range journal.backups {
+		released = append(released, segment.Data)
+		original = append(original, backup.fields.Data)
+
+		segment.SeriesSegmentFields = backup.fields
+
+		if s.tiering != nil {
+			s.tiering.reset(segment, backup.tiered, backup.cold)
+		}
+	}
+
+	// Segments are inserted back after their fields are restored to keep count of non-empty segments correct
+	onReplace := s.segments.onReplace
+	s.segments.onReplace = nil
+
+	for i := len(journal.replaces) - 1; i >= 0; i-- {
+		r := journal.replaces[i]
+		s.segments.Replace(r.from, r.from+r.inserted, r.removed...)
+	}
+
+	s.segments.onReplace = onReplace
+
+	for _, storage := range released {
+		if storage != nil && !slices.Contains(original, storage) && !slices.Contains(s.removed, storage) {
+			s.removed = append(s.removed, storage)
+		}
+	}
+
+	// Failure to restore one storage does not prevent restoring the others
+	var restoreErr error
+
+	for segment, backup := range journal.backups {
+		if err := backup.restoreData(); err != nil && restoreErr == nil {
+			restoreErr = errors.Wrapf(err, "failed to restore data of segment [ %v ; %v ]", segment.PeriodStart, segment.PeriodEnd)
+		}
+
+		if storage, ok := segment.Data.(BoundedSeriesData[Index]); ok {
+			if err := storage.SetBounds(segment.PeriodStart, segment.PeriodEnd); err != nil && restoreErr == nil {
+				restoreErr = err
+			}
+		}
+	}
+
+	if restoreErr != nil {
+		return restoreErr
+	}
+
+	return s.releaseRemovedStorages()
+}
+
+func (b *segmentBackup[Data, Index]) restoreData() error {
+	for i := len(b.changes) - 1; i >= 0; i-- {
+		change := b.changes[i]
+
+		if err := change.storage.Delete(change.periodStart, change.periodEnd); err != nil {
+			// Read-only storage could not be changed, so there is nothing to undo
+			if errors.Is(err, ErrReadOnly) {
+				continue
+			}
+
+			return err
+		}
+
+		if err := change.storage.Merge(change.data); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,87 @@
+package sparse_test
+
+import (
+	"errors"
+	"math"
+	"slices"
+	"testing"
+
+	"github.com/nnikolash/go-sparse"
+	"github.com/nnikolash/go-sparse/sparsetest"
+	"github.com/stretchr/testify/require"
+)
+
+type failingDeleteData struct {
+	sparse.SeriesData[int, int]
+	fail bool
+}
+
+func (s *failingDeleteData) Delete(periodStart, periodEnd int) error {
+	if s.fail {
+		return errors.New("delete failed")
+	}
+
+	return s.SeriesData.Delete(periodStart, periodEnd)
+}
+
+func TestSeries_FailedDeleteIsUndone(t *testing.T) {
+	t.Parallel()
+
+	series := sparse.NewSeries(
+		func(getIdx func(data *int) int, idxCmp func(idx1, idx2 int) int, periodStart, periodEnd int, data []int) (sparse.SeriesData[int, int], error) {
+			storage, err := sparse.NewArrayData(getIdx, idxCmp, periodStart, periodEnd, data)
+			return &failingDeleteData{SeriesData: storage}, err
+		},
+		func(data *int) int { return *data },
+		func(idx1, idx2 int) int { return idx1 - idx2 },
+		nil,
+	)
+
+	require.NoError(t, series.AddPeriod(10, 20, []int{10, 15, 20}))
+	require.NoError(t, series.AddPeriod(30, 40, []int{35}))
+	require.NoError(t, series.AddPeriod(50, 60, []int{55}))
+	expected := must2(series.MarshalJSON())
+
+	// The first segment is already cut and the second one is removed, when deletion fails in the last one
+	series.GetPeriod(50, 60).Data.(*failingDeleteData).fail = true
+	require.Error(t, series.DeletePeriod(15, 55))
+	require.Equal(t, string(expected), string(must2(series.MarshalJSON())))
+	require.NoError(t, series.Validate())
+	require.Len(t, slices.Collect(series.SegmentsBetween(0, 100, true)), 3)
+
+	series.GetPeriod(50, 60).Data.(*failingDeleteData).fail = false
+	require.NoError(t, series.DeletePeriod(15, 55))
+	require.Equal(t, []sparse.PeriodBounds[int]{{PeriodStart: 10, PeriodEnd: 10}, {PeriodStart: 60, PeriodEnd: 60}}, boundsOf(series.Segments()))
+}
+
+func TestSeries_FailedAddIsUndoneInFiles(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	newSeries := func() *sparse.Series[sparsetest.Item, int] {
+		return sparse.NewSeries(
+			sparse.NewFileDataFactory(dir, sparse.JSONCodec[sparsetest.Item, int]{}, sparse.FileDataOptions{}),
+			sparsetest.ItemIdx,
+			func(idx1, idx2 int) int { return idx1 - idx2 },
+			nil,
+		)
+	}
+
+	series := newSeries()
+	require.NoError(t, series.AddPeriod(10, 20, []sparsetest.Item{item(10, 1), item(20, 2)}))
+	require.NoError(t, series.AddPeriod(30, 40, []sparsetest.Item{item(35, 3)}))
+	require.NoError(t, series.AddPeriod(50, 60, []sparsetest.Item{item(55, 4)}))
+	expected := must2(series.MarshalJSON())
+
+	// Segments are merged into the last one, which fails to write data, after its old data of the period is deleted
+	require.Error(t, series.AddPeriod(18, 56, []sparsetest.Item{item(25, math.NaN())}))
+	require.Equal(t, string(expected), string(must2(series.MarshalJSON())))
+	require.Equal(t, 3, countFiles(t, dir))
+
+	restored := newSeries()
+	require.NoError(t, restored.RestoreFileDataDir(dir, sparse.JSONCodec[sparsetest.Item, int]{}, sparse.FileDataOptions{}))
+	require.Equal(t, string(expected), string(must2(restored.MarshalJSON())))
+
+	require.NoError(t, restored.AddPeriod(18, 56, []sparsetest.Item{item(25, 5)}))
+	require.Equal(t, 1, countFiles(t, dir))
+}
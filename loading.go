@@ -0,0 +1,317 @@
+package sparse
+
+import (
+	"context"
+	"slices"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+type Loader[Data any, Index any] interface {
+	// Returns all data of period [ periodStart ; periodEnd ], sorted by index.
+	Load(ctx context.Context, periodStart, periodEnd Index) ([]Data, error)
+}
+
+type LoaderFunc[Data any, Index any] func(ctx context.Context, periodStart, periodEnd Index) ([]Data, error)
+
+func (f LoaderFunc[Data, Index]) Load(ctx context.Context, periodStart, periodEnd Index) ([]Data, error) {
+	return f(ctx, periodStart, periodEnd)
+}
+
+type LoadingSeriesOptions[Index any] struct {
+	// Returns the biggest allowed end of a chunk, which starts at chunkStart.
+	// If not set, each missing period is loaded at once.
+	ChunkEnd func(chunkStart Index) Index
+	// Add periods, for which loader returned no data, to the series as empty periods.
+	AddEmptyPeriods bool
+}
+
+// Series, which loads missing periods using loader on each request.
+// It is safe for concurrent use, as long as underlying series is not accessed directly.
+// Concurrent requests wait for loading of overlapping periods instead of loading them again.
+// If loading fails because context of loading request is done, waiting requests load the period themselves.
+func NewLoadingSeries[Data any, Index any](
+	series *Series[Data, Index],
+	loader Loader[Data, Index],
+	opts LoadingSeriesOptions[Index],
+) *LoadingSeries[Data, Index] {
+	return &LoadingSeries[Data, Index]{
+		series: series,
+		loader: loader,
+		opts:   opts,
+	}
+}
+
+type LoadingSeries[Data any, Index any] struct {
+	series *Series[Data, Index]
+	loader Loader[Data, Index]
+	opts   LoadingSeriesOptions[Index]
+
+	mtx      sync.Mutex
+	inFlight []*inFlightLoad[Index]
+}
+
+type inFlightLoad[Index any] struct {
+	PeriodBounds[Index]
+	done chan struct{}
+	err  error
+	// Loaded, but not added to series, because there was no data
+	empty bool
+}
+
+func (s *LoadingSeries[Data, Index]) Series() *Series[Data, Index] {
+	return s.series
+}
+
+func (s *LoadingSeries[Data, Index]) Get(ctx context.Context, periodStart, periodEnd Index) ([]Data, error) {
+	if s.series.idxCmp(periodStart, periodEnd) > 0 {
+		return nil, errors.Errorf("requested period start is greater than period end: %v > %v", periodStart, periodEnd)
+	}
+
+	loads, err := s.loadMissing(ctx, PeriodBounds[Index]{PeriodStart: periodStart, PeriodEnd: periodEnd})
+	if err != nil {
+		return nil, err
+	}
+
+	var notAddedEmptyPeriods []PeriodBounds[Index]
+	for _, l := range loads {
+		if l.empty {
+			notAddedEmptyPeriods = append(notAddedEmptyPeriods, l.PeriodBounds)
+		}
+	}
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if len(notAddedEmptyPeriods) == 0 {
+		return s.series.Get(periodStart, periodEnd)
+	}
+
+	return s.getWithEmptyPeriods(periodStart, periodEnd, notAddedEmptyPeriods)
+}
+
+// Loads missing periods of the period or waits for their loading by other requests. Returns all completed loads.
+// Load of other request, which failed because context of that request was done, is started again.
+func (s *LoadingSeries[Data, Index]) loadMissing(ctx context.Context, period PeriodBounds[Index]) ([]*inFlightLoad[Index], error) {
+	var completed []*inFlightLoad[Index]
+
+	for periods := []PeriodBounds[Index]{period}; len(periods) != 0; {
+		ownLoads, otherLoads, err := s.startLoading(periods)
+		if err != nil {
+			return nil, err
+		}
+
+		var loadErr error
+		for _, l := range ownLoads {
+			if loadErr == nil {
+				loadErr = s.load(ctx, l)
+			}
+			s.finishLoading(l, loadErr)
+		}
+		if loadErr != nil {
+			return nil, loadErr
+		}
+
+		completed = append(completed, ownLoads...)
+		periods = nil
+
+		for _, l := range otherLoads {
+			select {
+			case <-l.done:
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+
+			if l.err != nil {
+				if isContextError(l.err) && ctx.Err() == nil {
+					retry := l.PeriodBounds
+					if s.series.idxCmp(retry.PeriodStart, period.PeriodStart) < 0 {
+						retry.PeriodStart = period.PeriodStart
+					}
+					if s.series.idxCmp(retry.PeriodEnd, period.PeriodEnd) > 0 {
+						retry.PeriodEnd = period.PeriodEnd
+					}
+					periods = append(periods, retry)
+					continue
+				}
+
+				return nil, l.err
+			}
+
+			completed = append(completed, l)
+		}
+	}
+
+	return completed, nil
+}
+
+func isContextError(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
+
+// Registers loads of missing periods, which are not yet being loaded by other requests.
+func (s *LoadingSeries[Data, Index]) startLoading(periods []PeriodBounds[Index]) (ownLoads, otherLoads []*inFlightLoad[Index], _ error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	var missingPeriods []PeriodBounds[Index]
+	for _, period := range periods {
+		missingPeriods = append(missingPeriods, s.series.MissingPeriods(period.PeriodStart, period.PeriodEnd)...)
+	}
+
+	for _, missing := range missingPeriods {
+		notLoading, loading := s.subtractInFlight(missing)
+		otherLoads = append(otherLoads, loading...)
+
+		for _, period := range notLoading {
+			chunks, err := s.splitToChunks(period)
+			if err != nil {
+				for _, l := range ownLoads {
+					s.finishLoadingLocked(l, err)
+				}
+				return nil, nil, err
+			}
+
+			for _, chunk := range chunks {
+				l := &inFlightLoad[Index]{PeriodBounds: chunk, done: make(chan struct{})}
+				s.inFlight = append(s.inFlight, l)
+				ownLoads = append(ownLoads, l)
+			}
+		}
+	}
+
+	return ownLoads, otherLoads, nil
+}
+
+func (s *LoadingSeries[Data, Index]) subtractInFlight(period PeriodBounds[Index]) (notLoading []PeriodBounds[Index], loading []*inFlightLoad[Index]) {
+	notLoading = []PeriodBounds[Index]{period}
+
+	for _, l := range s.inFlight {
+		var remaining []PeriodBounds[Index]
+		intersects := false
+
+		for _, p := range notLoading {
+			// Touching at single point is not considered as intersection - that point will be loaded anyway
+			if s.series.idxCmp(p.PeriodStart, l.PeriodEnd) >= 0 || s.series.idxCmp(p.PeriodEnd, l.PeriodStart) <= 0 {
+				remaining = append(remaining, p)
+				continue
+			}
+
+			intersects = true
+
+			if s.series.idxCmp(p.PeriodStart, l.PeriodStart) < 0 {
+				remaining = append(remaining, PeriodBounds[Index]{PeriodStart: p.PeriodStart, PeriodEnd: l.PeriodStart})
+			}
+			if s.series.idxCmp(p.PeriodEnd, l.PeriodEnd) > 0 {
+				remaining = append(remaining, PeriodBounds[Index]{PeriodStart: l.PeriodEnd, PeriodEnd: p.PeriodEnd})
+			}
+		}
+
+		if intersects {
+			loading = append(loading, l)
+		}
+
+		notLoading = remaining
+	}
+
+	return notLoading, loading
+}
+
+func (s *LoadingSeries[Data, Index]) load(ctx context.Context, l *inFlightLoad[Index]) error {
+	data, err := s.loader.Load(ctx, l.PeriodStart, l.PeriodEnd)
+	if err != nil {
+		return errors.Wrapf(err, "failed to load period [ %v ; %v ]", l.PeriodStart, l.PeriodEnd)
+	}
+
+	if len(data) == 0 && !s.opts.AddEmptyPeriods {
+		l.empty = true
+		return nil
+	}
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	return s.series.AddPeriod(l.PeriodStart, l.PeriodEnd, data)
+}
+
+func (s *LoadingSeries[Data, Index]) finishLoading(l *inFlightLoad[Index], err error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	s.finishLoadingLocked(l, err)
+}
+
+func (s *LoadingSeries[Data, Index]) finishLoadingLocked(l *inFlightLoad[Index], err error) {
+	l.err = err
+	s.inFlight = slices.DeleteFunc(s.inFlight, func(other *inFlightLoad[Index]) bool { return other == l })
+	close(l.done)
+}
+
+func (s *LoadingSeries[Data, Index]) splitToChunks(period PeriodBounds[Index]) ([]PeriodBounds[Index], error) {
+	if s.opts.ChunkEnd == nil {
+		return []PeriodBounds[Index]{period}, nil
+	}
+
+	var chunks []PeriodBounds[Index]
+
+	chunkStart := period.PeriodStart
+
+	for {
+		chunkEnd := s.opts.ChunkEnd(chunkStart)
+		if s.series.idxCmp(chunkEnd, chunkStart) <= 0 {
+			return nil, errors.Errorf("chunk end must be greater than chunk start: %v <= %v", chunkEnd, chunkStart)
+		}
+
+		if s.series.idxCmp(chunkEnd, period.PeriodEnd) >= 0 {
+			chunks = append(chunks, PeriodBounds[Index]{PeriodStart: chunkStart, PeriodEnd: period.PeriodEnd})
+			return chunks, nil
+		}
+
+		chunks = append(chunks, PeriodBounds[Index]{PeriodStart: chunkStart, PeriodEnd: chunkEnd})
+		chunkStart = chunkEnd
+	}
+}
+
+// Periods, for which loader returned nothing, are not present in the series, but they are known to be empty.
+func (s *LoadingSeries[Data, Index]) getWithEmptyPeriods(periodStart, periodEnd Index, emptyPeriods []PeriodBounds[Index]) ([]Data, error) {
+	available, missing, err := s.series.GetAvailable(periodStart, periodEnd)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, m := range missing {
+		if !s.periodsCover(emptyPeriods, m) {
+			return nil, errors.WithStack(&MissingPeriodError[Index]{PeriodStart: m.PeriodStart, PeriodEnd: m.PeriodEnd})
+		}
+	}
+
+	var res []Data
+	for _, a := range available {
+		res = append(res, a.Data...)
+	}
+
+	return res, nil
+}
+
+func (s *LoadingSeries[Data, Index]) periodsCover(periods []PeriodBounds[Index], period PeriodBounds[Index]) bool {
+	periods = slices.Clone(periods)
+	slices.SortFunc(periods, func(p1, p2 PeriodBounds[Index]) int {
+		return s.series.idxCmp(p1.PeriodStart, p2.PeriodStart)
+	})
+
+	coveredUntil := period.PeriodStart
+	covered := false
+
+	for _, p := range periods {
+		if s.series.idxCmp(p.PeriodStart, coveredUntil) > 0 {
+			break
+		}
+		if s.series.idxCmp(p.PeriodEnd, coveredUntil) >= 0 {
+			coveredUntil = p.PeriodEnd
+			covered = true
+		}
+	}
+
+	return covered && s.series.idxCmp(coveredUntil, period.PeriodEnd) >= 0
+}
@@ -0,0 +1,282 @@
+package sparse_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nnikolash/go-sparse"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Returns all multiples of 10 within the period, except ones from [ emptyStart ; emptyEnd ].
+func tensLoader(calls *[]sparse.PeriodBounds[int], emptyStart, emptyEnd int) sparse.LoaderFunc[int, int] {
+	return func(ctx context.Context, periodStart, periodEnd int) ([]int, error) {
+		*calls = append(*calls, sparse.PeriodBounds[int]{PeriodStart: periodStart, PeriodEnd: periodEnd})
+
+		var res []int
+		for i := periodStart; i <= periodEnd; i++ {
+			if i%10 == 0 && (i < emptyStart || i > emptyEnd) {
+				res = append(res, i)
+			}
+		}
+
+		return res, nil
+	}
+}
+
+func TestLoadingSeries_Get(t *testing.T) {
+	t.Parallel()
+
+	var calls []sparse.PeriodBounds[int]
+	series := sparse.NewLoadingSeries(intSparseSeries(), tensLoader(&calls, 0, -1), sparse.LoadingSeriesOptions[int]{})
+
+	res, err := series.Get(context.Background(), 10, 30)
+	require.NoError(t, err)
+	require.Equal(t, []int{10, 20, 30}, res)
+	require.Equal(t, []sparse.PeriodBounds[int]{{PeriodStart: 10, PeriodEnd: 30}}, calls)
+
+	calls = nil
+	res, err = series.Get(context.Background(), 15, 25)
+	require.NoError(t, err)
+	require.Equal(t, []int{20}, res)
+	require.Empty(t, calls)
+
+	calls = nil
+	res, err = series.Get(context.Background(), 60, 70)
+	require.NoError(t, err)
+	require.Equal(t, []int{60, 70}, res)
+	require.Equal(t, []sparse.PeriodBounds[int]{{PeriodStart: 60, PeriodEnd: 70}}, calls)
+
+	calls = nil
+	res, err = series.Get(context.Background(), 0, 80)
+	require.NoError(t, err)
+	require.Equal(t, []int{0, 10, 20, 30, 40, 50, 60, 70, 80}, res)
+	require.Equal(t, []sparse.PeriodBounds[int]{
+		{PeriodStart: 0, PeriodEnd: 10},
+		{PeriodStart: 30, PeriodEnd: 60},
+		{PeriodStart: 70, PeriodEnd: 80},
+	}, calls)
+	require.Len(t, series.Series().Segments(), 1)
+}
+
+func TestLoadingSeries_Chunks(t *testing.T) {
+	t.Parallel()
+
+	var calls []sparse.PeriodBounds[int]
+	series := sparse.NewLoadingSeries(intSparseSeries(), tensLoader(&calls, 0, -1), sparse.LoadingSeriesOptions[int]{
+		ChunkEnd: func(chunkStart int) int { return chunkStart + 25 },
+	})
+
+	res, err := series.Get(context.Background(), 0, 60)
+	require.NoError(t, err)
+	require.Equal(t, []int{0, 10, 20, 30, 40, 50, 60}, res)
+	require.Equal(t, []sparse.PeriodBounds[int]{
+		{PeriodStart: 0, PeriodEnd: 25},
+		{PeriodStart: 25, PeriodEnd: 50},
+		{PeriodStart: 50, PeriodEnd: 60},
+	}, calls)
+
+	series = sparse.NewLoadingSeries(intSparseSeries(), tensLoader(&calls, 0, -1), sparse.LoadingSeriesOptions[int]{
+		ChunkEnd: func(chunkStart int) int { return chunkStart },
+	})
+
+	_, err = series.Get(context.Background(), 0, 60)
+	require.Error(t, err)
+}
+
+func TestLoadingSeries_EmptyPeriods(t *testing.T) {
+	t.Parallel()
+
+	var calls []sparse.PeriodBounds[int]
+	series := sparse.NewLoadingSeries(intSparseSeries(), tensLoader(&calls, 21, 49), sparse.LoadingSeriesOptions[int]{
+		ChunkEnd: func(chunkStart int) int { return chunkStart + 10 },
+	})
+
+	res, err := series.Get(context.Background(), 0, 60)
+	require.NoError(t, err)
+	require.Equal(t, []int{0, 10, 20, 50, 60}, res)
+	require.Len(t, calls, 6)
+	require.Len(t, series.Series().Segments(), 2)
+
+	calls = nil
+	res, err = series.Get(context.Background(), 0, 60)
+	require.NoError(t, err)
+	require.Equal(t, []int{0, 10, 20, 50, 60}, res)
+	require.Equal(t, []sparse.PeriodBounds[int]{{PeriodStart: 30, PeriodEnd: 40}}, calls)
+
+	series = sparse.NewLoadingSeries(intSparseSeries(), tensLoader(&calls, 21, 49), sparse.LoadingSeriesOptions[int]{
+		ChunkEnd:        func(chunkStart int) int { return chunkStart + 10 },
+		AddEmptyPeriods: true,
+	})
+
+	res, err = series.Get(context.Background(), 0, 60)
+	require.NoError(t, err)
+	require.Equal(t, []int{0, 10, 20, 50, 60}, res)
+	require.Len(t, series.Series().Segments(), 1)
+
+	calls = nil
+	res, err = series.Get(context.Background(), 0, 60)
+	require.NoError(t, err)
+	require.Equal(t, []int{0, 10, 20, 50, 60}, res)
+	require.Empty(t, calls)
+}
+
+func TestLoadingSeries_ConcurrentLoads(t *testing.T) {
+	t.Parallel()
+
+	var callsMtx sync.Mutex
+	var calls []sparse.PeriodBounds[int]
+	release := make(chan struct{})
+
+	loader := sparse.LoaderFunc[int, int](func(ctx context.Context, periodStart, periodEnd int) ([]int, error) {
+		callsMtx.Lock()
+		calls = append(calls, sparse.PeriodBounds[int]{PeriodStart: periodStart, PeriodEnd: periodEnd})
+		callsMtx.Unlock()
+
+		<-release
+
+		var res []int
+		for i := periodStart; i <= periodEnd; i++ {
+			if i%10 == 0 {
+				res = append(res, i)
+			}
+		}
+
+		return res, nil
+	})
+
+	callsCount := func() int {
+		callsMtx.Lock()
+		defer callsMtx.Unlock()
+		return len(calls)
+	}
+
+	series := sparse.NewLoadingSeries(intSparseSeries(), loader, sparse.LoadingSeriesOptions[int]{})
+
+	var wg sync.WaitGroup
+	get := func(periodStart, periodEnd int, expected []int) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			res, err := series.Get(context.Background(), periodStart, periodEnd)
+			assert.NoError(t, err)
+			assert.Equal(t, expected, res)
+		}()
+	}
+
+	get(0, 100, []int{0, 10, 20, 30, 40, 50, 60, 70, 80, 90, 100})
+	require.Eventually(t, func() bool { return callsCount() == 1 }, time.Second, time.Millisecond)
+
+	get(20, 50, []int{20, 30, 40, 50})
+	get(20, 50, []int{20, 30, 40, 50})
+	get(50, 150, []int{50, 60, 70, 80, 90, 100, 110, 120, 130, 140, 150})
+	require.Eventually(t, func() bool { return callsCount() == 2 }, time.Second, time.Millisecond)
+
+	close(release)
+	wg.Wait()
+
+	require.ElementsMatch(t, []sparse.PeriodBounds[int]{
+		{PeriodStart: 0, PeriodEnd: 100},
+		{PeriodStart: 100, PeriodEnd: 150},
+	}, calls)
+}
+
+func TestLoadingSeries_ConcurrentLoadError(t *testing.T) {
+	t.Parallel()
+
+	started := make(chan struct{})
+	var startedOnce sync.Once
+	release := make(chan struct{})
+
+	loader := sparse.LoaderFunc[int, int](func(ctx context.Context, periodStart, periodEnd int) ([]int, error) {
+		startedOnce.Do(func() { close(started) })
+		<-release
+		return nil, errors.New("source is unavailable")
+	})
+
+	series := sparse.NewLoadingSeries(intSparseSeries(), loader, sparse.LoadingSeriesOptions[int]{})
+
+	firstErr := make(chan error)
+	go func() {
+		_, err := series.Get(context.Background(), 0, 100)
+		firstErr <- err
+	}()
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := series.Get(ctx, 20, 50)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	secondErr := make(chan error)
+	go func() {
+		_, err := series.Get(context.Background(), 20, 50)
+		secondErr <- err
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+
+	require.ErrorContains(t, <-firstErr, "source is unavailable")
+	require.ErrorContains(t, <-secondErr, "source is unavailable")
+}
+
+func TestLoadingSeries_ConcurrentLoadCancelledByOtherRequest(t *testing.T) {
+	t.Parallel()
+
+	started := make(chan struct{})
+	var startedOnce sync.Once
+	var mtx sync.Mutex
+	var calls []sparse.PeriodBounds[int]
+
+	loader := sparse.LoaderFunc[int, int](func(ctx context.Context, periodStart, periodEnd int) ([]int, error) {
+		mtx.Lock()
+		calls = append(calls, sparse.PeriodBounds[int]{PeriodStart: periodStart, PeriodEnd: periodEnd})
+		mtx.Unlock()
+
+		first := false
+		startedOnce.Do(func() { first = true; close(started) })
+		if first {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		}
+
+		return []int{periodStart, periodEnd}, nil
+	})
+
+	series := sparse.NewLoadingSeries(intSparseSeries(), loader, sparse.LoadingSeriesOptions[int]{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	firstErr := make(chan error)
+	go func() {
+		_, err := series.Get(ctx, 0, 100)
+		firstErr <- err
+	}()
+	<-started
+
+	secondRes := make(chan []int)
+	secondErr := make(chan error)
+	go func() {
+		res, err := series.Get(context.Background(), 20, 50)
+		secondRes <- res
+		secondErr <- err
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+	cancel()
+
+	require.ErrorIs(t, <-firstErr, context.Canceled)
+	require.Equal(t, []int{20, 50}, <-secondRes)
+	require.NoError(t, <-secondErr)
+
+	mtx.Lock()
+	defer mtx.Unlock()
+	require.Equal(t, []sparse.PeriodBounds[int]{
+		{PeriodStart: 0, PeriodEnd: 100},
+		{PeriodStart: 20, PeriodEnd: 50},
+	}, calls)
+}
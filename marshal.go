@@ -0,0 +1,273 @@
+package sparse
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	binaryFormatMagic   = "SPRS"
+	binaryFormatVersion = 1
+)
+
+// Sets codec used by MarshalBinary and UnmarshalBinary. By default JSONCodec is used.
+func (s *Series[Data, Index]) SetCodec(codec Codec[Data, Index]) {
+	s.codec = codec
+}
+
+func (s *Series[Data, Index]) getCodec() Codec[Data, Index] {
+	if s.codec == nil {
+		return JSONCodec[Data, Index]{}
+	}
+
+	return s.codec
+}
+
+type segmentData[Data any, Index any] struct {
+	PeriodBounds[Index]
+	Empty bool
+	Data  []Data
+}
+
+type segmentJSON[Data any, Index any] struct {
+	PeriodStart Index  `json:"periodStart"`
+	PeriodEnd   Index  `json:"periodEnd"`
+	Empty       bool   `json:"empty"`
+	Data        []Data `json:"data"`
+}
+
+type seriesJSON[Data any, Index any] struct {
+	Segments []segmentJSON[Data, Index] `json:"segments"`
+}
+
+// Encodes segments of series together with their data. Indexes and data are encoded with encoding/json.
+func (s *Series[Data, Index]) MarshalJSON() ([]byte, error) {
+	segments, err := s.getSegmentsData()
+	if err != nil {
+		return nil, err
+	}
+
+	res := seriesJSON[Data, Index]{Segments: make([]segmentJSON[Data, Index], 0, len(segments))}
+	for _, segment := range segments {
+		res.Segments = append(res.Segments, segmentJSON[Data, Index]{
+			PeriodStart: segment.PeriodStart,
+			PeriodEnd:   segment.PeriodEnd,
+			Empty:       segment.Empty,
+			Data:        segment.Data,
+		})
+	}
+
+	b, err := json.Marshal(res)
+	return b, errors.Wrap(err, "failed to marshal series")
+}
+
+// Replaces content of series with decoded segments. Storages of segments are created by the series data factory.
+func (s *Series[Data, Index]) UnmarshalJSON(b []byte) error {
+	var decoded seriesJSON[Data, Index]
+	if err := json.Unmarshal(b, &decoded); err != nil {
+		return errors.Wrap(err, "failed to unmarshal series")
+	}
+
+	segments := make([]segmentData[Data, Index], 0, len(decoded.Segments))
+	for _, segment := range decoded.Segments {
+		segments = append(segments, segmentData[Data, Index]{
+			PeriodBounds: PeriodBounds[Index]{PeriodStart: segment.PeriodStart, PeriodEnd: segment.PeriodEnd},
+			Empty:        segment.Empty,
+			Data:         segment.Data,
+		})
+	}
+
+	return s.restoreSegmentsData(segments)
+}
+
+// Encodes segments of series together with their data. Indexes and data are encoded with the series codec.
+func (s *Series[Data, Index]) MarshalBinary() ([]byte, error) {
+	return s.marshalBinary(s.getCodec())
+}
+
+func (s *Series[Data, Index]) marshalBinary(codec Codec[Data, Index]) ([]byte, error) {
+	segments, err := s.getSegmentsData()
+	if err != nil {
+		return nil, err
+	}
+
+	b := append([]byte(binaryFormatMagic), binaryFormatVersion)
+	b = binary.AppendUvarint(b, uint64(len(segments)))
+
+	for _, segment := range segments {
+		var flags byte
+		if segment.Empty {
+			flags = 1
+		}
+		b = append(b, flags)
+
+		periodStart, err := codec.EncodeIndex(segment.PeriodStart)
+		if err != nil {
+			return nil, err
+		}
+		b = appendBytes(b, periodStart)
+
+		periodEnd, err := codec.EncodeIndex(segment.PeriodEnd)
+		if err != nil {
+			return nil, err
+		}
+		b = appendBytes(b, periodEnd)
+
+		data, err := codec.EncodeData(segment.Data)
+		if err != nil {
+			return nil, err
+		}
+		b = appendBytes(b, data)
+	}
+
+	return b, nil
+}
+
+// Replaces content of series with decoded segments. Storages of segments are created by the series data factory.
+func (s *Series[Data, Index]) UnmarshalBinary(b []byte) error {
+	return s.unmarshalBinary(b, s.getCodec())
+}
+
+func (s *Series[Data, Index]) unmarshalBinary(b []byte, codec Codec[Data, Index]) error {
+	r := bytes.NewReader(b)
+
+	header := make([]byte, len(binaryFormatMagic)+1)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return errors.Wrap(err, "failed to read header")
+	}
+	if string(header[:len(binaryFormatMagic)]) != binaryFormatMagic {
+		return errors.Errorf("invalid format: unexpected magic %q", header[:len(binaryFormatMagic)])
+	}
+	if header[len(binaryFormatMagic)] != binaryFormatVersion {
+		return errors.Errorf("unsupported format version: %v", header[len(binaryFormatMagic)])
+	}
+
+	count, err := binary.ReadUvarint(r)
+	if err != nil {
+		return errors.Wrap(err, "failed to read segments count")
+	}
+	if count > uint64(r.Len()) {
+		return errors.Errorf("invalid segments count: %v", count)
+	}
+
+	segments := make([]segmentData[Data, Index], 0, count)
+
+	for i := uint64(0); i < count; i++ {
+		flags, err := r.ReadByte()
+		if err != nil {
+			return errors.Wrapf(err, "failed to read segment %v", i)
+		}
+
+		var segment segmentData[Data, Index]
+		segment.Empty = flags&1 != 0
+
+		b, err := readBytes(r)
+		if err != nil {
+			return errors.Wrapf(err, "failed to read segment %v", i)
+		}
+		if segment.PeriodStart, err = codec.DecodeIndex(b); err != nil {
+			return err
+		}
+
+		if b, err = readBytes(r); err != nil {
+			return errors.Wrapf(err, "failed to read segment %v", i)
+		}
+		if segment.PeriodEnd, err = codec.DecodeIndex(b); err != nil {
+			return err
+		}
+
+		if b, err = readBytes(r); err != nil {
+			return errors.Wrapf(err, "failed to read segment %v", i)
+		}
+		if segment.Data, err = codec.DecodeData(b); err != nil {
+			return err
+		}
+
+		segments = append(segments, segment)
+	}
+
+	if r.Len() != 0 {
+		return errors.Errorf("invalid format: %v unexpected trailing bytes", r.Len())
+	}
+
+	return s.restoreSegmentsData(segments)
+}
+
+func (s *Series[Data, Index]) getSegmentsData() ([]segmentData[Data, Index], error) {
+	segments := make([]segmentData[Data, Index], 0, s.segments.Len())
+
+	for segment := range s.segments.Values(0) {
+		data, err := segment.GetAll()
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to get data of segment [ %v ; %v ]", segment.PeriodStart, segment.PeriodEnd)
+		}
+
+		segments = append(segments, segmentData[Data, Index]{
+			PeriodBounds: segment.PeriodBounds,
+			Empty:        segment.Empty,
+			Data:         data,
+		})
+	}
+
+	return segments, nil
+}
+
+// Creates storages of segments and replaces content of the series with them.
+// If restore fails, already created storages are released.
+func (s *Series[Data, Index]) restoreSegmentsData(segments []segmentData[Data, Index]) error {
+	state := &SeriesState[Data, Index]{Segments: make([]*SeriesSegmentFields[Data, Index], 0, len(segments))}
+
+	release := func() {
+		for _, segment := range state.Segments {
+			_ = releaseStorage(segment.Data)
+		}
+	}
+
+	for _, segment := range segments {
+		storage, err := s.dataFactory(s.getIdx, s.idxCmp, segment.PeriodStart, segment.PeriodEnd, segment.Data)
+		if err != nil {
+			release()
+			return err
+		}
+
+		state.Segments = append(state.Segments, &SeriesSegmentFields[Data, Index]{
+			PeriodBounds: segment.PeriodBounds,
+			Data:         storage,
+			Empty:        segment.Empty,
+		})
+	}
+
+	restored, err := s.restoredSegments(state)
+	if err != nil {
+		release()
+		return err
+	}
+
+	return s.replaceSegments(restored)
+}
+
+func appendBytes(b []byte, v []byte) []byte {
+	b = binary.AppendUvarint(b, uint64(len(v)))
+	return append(b, v...)
+}
+
+func readBytes(r *bytes.Reader) ([]byte, error) {
+	size, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, err
+	}
+	if size > uint64(r.Len()) {
+		return nil, errors.Errorf("invalid length: %v > %v", size, r.Len())
+	}
+
+	b := make([]byte, size)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
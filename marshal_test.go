@@ -0,0 +1,178 @@
+package sparse_test
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/nnikolash/go-sparse"
+	"github.com/stretchr/testify/require"
+)
+
+// Encodes indexes and data as fixed-size integers.
+type intBinaryCodec struct{}
+
+func (intBinaryCodec) EncodeIndex(idx int) ([]byte, error) {
+	return binary.BigEndian.AppendUint64(nil, uint64(idx)), nil
+}
+
+func (intBinaryCodec) DecodeIndex(b []byte) (int, error) {
+	return int(binary.BigEndian.Uint64(b)), nil
+}
+
+func (intBinaryCodec) EncodeData(data []int) ([]byte, error) {
+	b := make([]byte, 0, 8*len(data))
+	for _, v := range data {
+		b = binary.BigEndian.AppendUint64(b, uint64(v))
+	}
+	return b, nil
+}
+
+func (intBinaryCodec) DecodeData(b []byte) ([]int, error) {
+	data := make([]int, 0, len(b)/8)
+	for i := 0; i+8 <= len(b); i += 8 {
+		data = append(data, int(binary.BigEndian.Uint64(b[i:])))
+	}
+	return data, nil
+}
+
+func filledSeries(t *testing.T) *sparse.Series[int, int] {
+	series := intSparseSeries()
+	require.NoError(t, series.AddPeriod(10, 20, []int{10, 15, 20}))
+	require.NoError(t, series.AddPeriod(30, 40, nil))
+	require.NoError(t, series.AddPeriod(50, 60, []int{55}))
+	return series
+}
+
+func requireSameSeries(t *testing.T, expected, actual *sparse.Series[int, int]) {
+	require.Equal(t, len(expected.Segments()), len(actual.Segments()))
+
+	for i, segment := range expected.Segments() {
+		restored := actual.Segments()[i]
+		require.Equal(t, segment.PeriodBounds, restored.PeriodBounds)
+		require.Equal(t, segment.Empty, restored.Empty)
+
+		data, err := restored.GetAll()
+		require.NoError(t, err)
+		expectedData, err := segment.GetAll()
+		require.NoError(t, err)
+		require.Equal(t, len(expectedData), len(data))
+		if len(data) != 0 {
+			require.Equal(t, expectedData, data)
+		}
+	}
+}
+
+func TestSeries_MarshalJSON(t *testing.T) {
+	t.Parallel()
+
+	series := filledSeries(t)
+
+	b, err := json.Marshal(series)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"segments":[
+		{"periodStart":10,"periodEnd":20,"empty":false,"data":[10,15,20]},
+		{"periodStart":30,"periodEnd":40,"empty":true,"data":[]},
+		{"periodStart":50,"periodEnd":60,"empty":false,"data":[55]}
+	]}`, string(b))
+
+	restored := intSparseSeries()
+	require.NoError(t, json.Unmarshal(b, restored))
+	requireSameSeries(t, series, restored)
+
+	res, err := restored.Get(10, 20)
+	require.NoError(t, err)
+	require.Equal(t, []int{10, 15, 20}, res)
+
+	err = restored.UnmarshalJSON([]byte(`{"segments":[{"periodStart":10,"periodEnd":20,"empty":true,"data":[15]}]}`))
+	require.ErrorIs(t, err, sparse.ErrStorageCorrupted)
+	requireSameSeries(t, series, restored)
+}
+
+func TestSeries_MarshalBinary(t *testing.T) {
+	t.Parallel()
+
+	for name, codec := range map[string]sparse.Codec[int, int]{
+		"default": nil,
+		"json":    sparse.JSONCodec[int, int]{},
+		"gob":     sparse.GobCodec[int, int]{},
+		"custom":  intBinaryCodec{},
+	} {
+		series := filledSeries(t)
+		series.SetCodec(codec)
+
+		b, err := series.MarshalBinary()
+		require.NoError(t, err, name)
+
+		factoryCalls := 0
+		restored := sparse.NewSeries[int, int](
+			func(getIdx func(data *int) int, idxCmp func(idx1, idx2 int) int, periodStart, periodEnd int, data []int) (sparse.SeriesData[int, int], error) {
+				factoryCalls++
+				return sparse.NewArrayData(getIdx, idxCmp, periodStart, periodEnd, data)
+			},
+			func(data *int) int { return *data },
+			func(idx1, idx2 int) int { return idx1 - idx2 },
+			func(smaller, bigger int) bool { return bigger-smaller == 1 },
+		)
+		restored.SetCodec(codec)
+
+		require.NoError(t, restored.UnmarshalBinary(b), name)
+		require.Equal(t, 3, factoryCalls, name)
+		requireSameSeries(t, series, restored)
+
+		snapshot, err := series.Snapshot()
+		require.NoError(t, err)
+		snapshotBytes, err := snapshot.MarshalBinary()
+		require.NoError(t, err)
+		require.Equal(t, b, snapshotBytes, name)
+
+		require.Error(t, restored.UnmarshalBinary(b[:len(b)-1]), name)
+		require.Error(t, restored.UnmarshalBinary(append(b, 0)), name)
+		require.Error(t, restored.UnmarshalBinary(append([]byte("XXXX"), b[4:]...)), name)
+		require.Error(t, restored.UnmarshalBinary(nil), name)
+		requireSameSeries(t, series, restored)
+	}
+}
+
+func TestSeries_MarshalBinaryEmpty(t *testing.T) {
+	t.Parallel()
+
+	series := intSparseSeries()
+
+	b, err := series.MarshalBinary()
+	require.NoError(t, err)
+
+	restored := filledSeries(t)
+	require.NoError(t, restored.UnmarshalBinary(b))
+	require.Empty(t, restored.Segments())
+}
+
+func TestSeries_UnmarshalReleasesStoragesOnError(t *testing.T) {
+	t.Parallel()
+
+	b, err := filledSeries(t).MarshalBinary()
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	fileFactory := sparse.NewFileDataFactory(dir, sparse.JSONCodec[int, int]{}, sparse.FileDataOptions{})
+
+	factoryCalls := 0
+	restored := sparse.NewSeries[int, int](
+		func(getIdx func(data *int) int, idxCmp func(idx1, idx2 int) int, periodStart, periodEnd int, data []int) (sparse.SeriesData[int, int], error) {
+			factoryCalls++
+			if factoryCalls == 3 {
+				return nil, errors.New("storage is unavailable")
+			}
+			return fileFactory(getIdx, idxCmp, periodStart, periodEnd, data)
+		},
+		func(data *int) int { return *data },
+		func(idx1, idx2 int) int { return idx1 - idx2 },
+		nil,
+	)
+
+	require.ErrorContains(t, restored.UnmarshalBinary(b), "storage is unavailable")
+	require.Equal(t, 3, factoryCalls)
+	require.Equal(t, 0, countFiles(t, dir))
+	require.Empty(t, restored.Segments())
+}
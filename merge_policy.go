@@ -0,0 +1,124 @@
+package sparse
+
+// Describes how new data is combined with existing data of the merged period.
+// Nil policy means that new data replaces all existing data of the period.
+// All items with the same index are treated as a group.
+type MergePolicy[Data any] struct {
+	// Combines existing and new item with the same index. Groups are combined item by item in their order,
+	// and items, which have no pair in another group, are stored as is. If set, KeepMatched is ignored.
+	Resolve func(old, new *Data) Data
+	// Keep existing items at indexes, which are present in new data. Otherwise new items replace them.
+	KeepMatched bool
+	// Keep existing items at indexes, which are not present in new data. Otherwise they are removed.
+	KeepUnmatched bool
+}
+
+// New data replaces all existing data of the merged period.
+func MergeOverwrite[Data any]() *MergePolicy[Data] {
+	return &MergePolicy[Data]{}
+}
+
+// Existing data is never replaced. New items are added only at indexes, which have no data yet.
+func MergeKeepExisting[Data any]() *MergePolicy[Data] {
+	return &MergePolicy[Data]{
+		KeepMatched:   true,
+		KeepUnmatched: true,
+	}
+}
+
+// Items with the same index are combined using resolve. Other existing items are kept.
+func MergeResolve[Data any](resolve func(old, new *Data) Data) *MergePolicy[Data] {
+	return &MergePolicy[Data]{
+		Resolve:       resolve,
+		KeepUnmatched: true,
+	}
+}
+
+func (p *MergePolicy[Data]) isOverwrite() bool {
+	return p == nil || (p.Resolve == nil && !p.KeepMatched && !p.KeepUnmatched)
+}
+
+// Combines sorted existing data with sorted new data.
+func combineData[Data any, Index any](policy *MergePolicy[Data], getIdx func(data *Data) Index, idxCmp func(idx1, idx2 Index) int, old, new []Data) []Data {
+	if policy.isOverwrite() || len(old) == 0 {
+		return new
+	}
+
+	groupEnd := func(data []Data, start int) int {
+		idx := getIdx(&data[start])
+		end := start + 1
+		for end < len(data) && idxCmp(getIdx(&data[end]), idx) == 0 {
+			end++
+		}
+		return end
+	}
+
+	res := make([]Data, 0, len(old)+len(new))
+
+	i, j := 0, 0
+	for i < len(old) || j < len(new) {
+		var cmp int
+		switch {
+		case i == len(old):
+			cmp = 1
+		case j == len(new):
+			cmp = -1
+		default:
+			cmp = idxCmp(getIdx(&old[i]), getIdx(&new[j]))
+		}
+
+		switch {
+		case cmp < 0:
+			if policy.KeepUnmatched {
+				res = append(res, old[i])
+			}
+			i++
+		case cmp > 0:
+			res = append(res, new[j])
+			j++
+		default:
+			oldEnd, newEnd := groupEnd(old, i), groupEnd(new, j)
+
+			switch {
+			case policy.Resolve != nil:
+				for ; i < oldEnd && j < newEnd; i, j = i+1, j+1 {
+					res = append(res, policy.Resolve(&old[i], &new[j]))
+				}
+				res = append(res, old[i:oldEnd]...)
+				res = append(res, new[j:newEnd]...)
+			case policy.KeepMatched:
+				res = append(res, old[i:oldEnd]...)
+			default:
+				res = append(res, new[j:newEnd]...)
+			}
+
+			i, j = oldEnd, newEnd
+		}
+	}
+
+	return res
+}
+
+// Sets policy of combining added data with existing data. Nil means that added data replaces existing data of the period.
+func (s *Series[Data, Index]) SetMergePolicy(policy *MergePolicy[Data]) {
+	s.mergePolicy = policy
+}
+
+func (s *Series[Data, Index]) combineWithExisting(periodStart, periodEnd Index, data []Data) ([]Data, error) {
+	if s.mergePolicy.isOverwrite() || s.idxCmp(periodStart, periodEnd) > 0 {
+		return data, nil
+	}
+
+	var existing []Data
+
+	for segment := range s.SegmentsBetween(periodStart, periodEnd, true) {
+		_, _, segmentData, err := segment.GetAllInRange(periodStart, periodEnd)
+		if err != nil {
+			return nil, err
+		}
+
+		existing = append(existing, segmentData...)
+	}
+
+	return combineData(s.mergePolicy, s.getIdx, s.idxCmp, existing, data), nil
+}
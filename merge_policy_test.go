@@ -0,0 +1,210 @@
+package sparse_test
+
+import (
+	"testing"
+
+	"github.com/nnikolash/go-sparse"
+	"github.com/nnikolash/go-sparse/sparsetest"
+	"github.com/stretchr/testify/require"
+)
+
+func itemSparseSeries(factory sparse.SeriesDataFactory[sparsetest.Item, int]) *sparse.Series[sparsetest.Item, int] {
+	return sparse.NewSeries(
+		factory,
+		sparsetest.ItemIdx,
+		sparsetest.ItemIdxCmp,
+		func(smaller, bigger int) bool { return bigger-smaller == 1 },
+	)
+}
+
+func item(idx int, val float64) sparsetest.Item {
+	return sparsetest.Item{Idx: idx, Val: val}
+}
+
+func sumItems(old, new *sparsetest.Item) sparsetest.Item {
+	return sparsetest.Item{Idx: old.Idx, Val: old.Val + new.Val}
+}
+
+func TestSeries_MergePolicy(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		policy   *sparse.MergePolicy[sparsetest.Item]
+		expected []sparsetest.Item
+	}{
+		"nil": {
+			policy:   nil,
+			expected: []sparsetest.Item{item(1, 1), item(3, 30), item(4, 40), item(6, 6)},
+		},
+		"overwrite": {
+			policy:   sparse.MergeOverwrite[sparsetest.Item](),
+			expected: []sparsetest.Item{item(1, 1), item(3, 30), item(4, 40), item(6, 6)},
+		},
+		"keep existing": {
+			policy:   sparse.MergeKeepExisting[sparsetest.Item](),
+			expected: []sparsetest.Item{item(1, 1), item(2, 2), item(3, 3), item(4, 40), item(5, 5), item(6, 6)},
+		},
+		"resolve": {
+			policy:   sparse.MergeResolve(sumItems),
+			expected: []sparsetest.Item{item(1, 1), item(2, 2), item(3, 33), item(4, 40), item(5, 5), item(6, 6)},
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			series := itemSparseSeries(sparse.NewArrayData)
+			series.SetMergePolicy(c.policy)
+
+			require.NoError(t, series.AddPeriod(1, 3, sparsetest.Items(1, 1, 2, 3)))
+			require.NoError(t, series.AddPeriod(5, 6, sparsetest.Items(1, 5, 6)))
+			require.NoError(t, series.AddPeriod(2, 5, sparsetest.Items(10, 3, 4)))
+			require.NoError(t, series.Validate())
+
+			require.Len(t, series.Segments(), 1)
+			res, err := series.Get(1, 6)
+			require.NoError(t, err)
+			require.Equal(t, c.expected, res)
+		})
+	}
+}
+
+func TestArrayData_MergePolicy(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		policy   *sparse.MergePolicy[sparsetest.Item]
+		expected []sparsetest.Item
+	}{
+		"overwrite": {
+			policy:   sparse.MergeOverwrite[sparsetest.Item](),
+			expected: []sparsetest.Item{item(1, 1), item(2, 2), item(3, 30), item(5, 50), item(6, 6)},
+		},
+		"keep existing": {
+			policy:   sparse.MergeKeepExisting[sparsetest.Item](),
+			expected: []sparsetest.Item{item(1, 1), item(2, 2), item(3, 3), item(4, 4), item(5, 50), item(6, 6)},
+		},
+		"resolve": {
+			policy:   sparse.MergeResolve(sumItems),
+			expected: []sparsetest.Item{item(1, 1), item(2, 2), item(3, 33), item(4, 4), item(5, 50), item(6, 6)},
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			factory := sparse.NewArrayDataWithPolicy[sparsetest.Item, int](c.policy)
+
+			data, err := factory(sparsetest.ItemIdx, sparsetest.ItemIdxCmp, 1, 4, sparsetest.Items(1, 1, 2, 3, 4, 6))
+			require.NoError(t, err)
+			require.NoError(t, data.Merge(sparsetest.Items(10, 3, 5)))
+
+			res, err := data.Get(1, 6)
+			require.NoError(t, err)
+			require.Equal(t, c.expected, res)
+		})
+	}
+}
+
+func TestSeries_StorageMergePolicy(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		policy   *sparse.MergePolicy[sparsetest.Item]
+		expected []sparsetest.Item
+	}{
+		"overwrite": {
+			policy:   sparse.MergeOverwrite[sparsetest.Item](),
+			expected: []sparsetest.Item{item(50, 500)},
+		},
+		"keep existing": {
+			policy:   sparse.MergeKeepExisting[sparsetest.Item](),
+			expected: []sparsetest.Item{item(10, 10), item(50, 50), item(90, 90)},
+		},
+		"resolve": {
+			policy:   sparse.MergeResolve(sumItems),
+			expected: []sparsetest.Item{item(10, 10), item(50, 550), item(90, 90)},
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			series := itemSparseSeries(sparse.NewArrayDataWithPolicy[sparsetest.Item, int](c.policy))
+			series.SetValidateOnChange(true)
+
+			require.NoError(t, series.AddPeriod(0, 100, sparsetest.Items(1, 10, 50, 90)))
+			require.NoError(t, series.AddPeriod(0, 100, []sparsetest.Item{item(50, 500)}))
+
+			require.Len(t, series.Segments(), 1)
+			require.Equal(t, c.expected, must2(series.Get(0, 100)))
+		})
+	}
+}
+
+func TestSeries_SeriesAndStorageMergePolicy(t *testing.T) {
+	t.Parallel()
+
+	// Series policy is applied instead of storage policy, so items are combined only once
+	series := itemSparseSeries(sparse.NewArrayDataWithPolicy[sparsetest.Item, int](sparse.MergeResolve(sumItems)))
+	series.SetMergePolicy(sparse.MergeResolve(sumItems))
+
+	require.NoError(t, series.AddPeriod(0, 100, sparsetest.Items(1, 10, 50, 90)))
+	require.NoError(t, series.AddPeriod(40, 60, []sparsetest.Item{item(50, 500)}))
+	require.Equal(t, []sparsetest.Item{item(10, 10), item(50, 550), item(90, 90)}, must2(series.Get(0, 100)))
+
+	series = itemSparseSeries(sparse.NewArrayDataWithPolicy[sparsetest.Item, int](sparse.MergeKeepExisting[sparsetest.Item]()))
+	series.SetMergePolicy(sparse.MergeResolve(sumItems))
+
+	require.NoError(t, series.AddPeriod(0, 100, sparsetest.Items(1, 10, 50, 90)))
+	require.NoError(t, series.AddPeriod(0, 100, []sparsetest.Item{item(50, 500), item(60, 600)}))
+	require.Equal(t, []sparsetest.Item{item(10, 10), item(50, 550), item(60, 600), item(90, 90)}, must2(series.Get(0, 100)))
+}
+
+func TestArrayData_MergePolicyConformance(t *testing.T) {
+	t.Parallel()
+
+	sparsetest.RunSeriesDataConformance(t, sparse.NewArrayDataWithPolicy[sparsetest.Item, int](sparse.MergeOverwrite[sparsetest.Item]()), sparsetest.ItemFixture())
+}
+
+func TestSeries_MergePolicyDuplicates(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		policy   *sparse.MergePolicy[sparsetest.Item]
+		expected []sparsetest.Item
+	}{
+		"overwrite": {
+			policy:   sparse.MergeOverwrite[sparsetest.Item](),
+			expected: []sparsetest.Item{item(1, 1), item(2, 10), item(3, 10), item(3, 20), item(3, 30)},
+		},
+		"keep existing": {
+			policy:   sparse.MergeKeepExisting[sparsetest.Item](),
+			expected: []sparsetest.Item{item(1, 1), item(2, 1), item(2, 2), item(3, 10), item(3, 20), item(3, 30)},
+		},
+		"resolve": {
+			policy:   sparse.MergeResolve(sumItems),
+			expected: []sparsetest.Item{item(1, 1), item(2, 11), item(2, 2), item(3, 10), item(3, 20), item(3, 30)},
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			series := itemSparseSeries(sparse.NewArrayData)
+			series.SetMergePolicy(c.policy)
+
+			require.NoError(t, series.AddPeriod(1, 2, []sparsetest.Item{item(1, 1), item(2, 1), item(2, 2)}))
+			require.NoError(t, series.AddPeriod(2, 3, []sparsetest.Item{item(2, 10), item(3, 10), item(3, 20), item(3, 30)}))
+			require.NoError(t, series.Validate())
+
+			res, err := series.Get(1, 3)
+			require.NoError(t, err)
+			require.Equal(t, c.expected, res)
+		})
+	}
+}
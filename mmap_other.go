@@ -0,0 +1,17 @@
+//go:build !unix
+
+package sparse
+
+import (
+	"os"
+
+	"github.com/pkg/errors"
+)
+
+func mapFile(f *os.File, size int) ([]byte, error) {
+	return nil, errors.New("memory-mapped files are not supported on this platform")
+}
+
+func unmapFile(b []byte) error {
+	return nil
+}
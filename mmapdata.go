@@ -0,0 +1,481 @@
+package sparse
+
+import (
+	"iter"
+	"os"
+	"slices"
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+// Encodes data item into record of fixed size and decodes it back.
+type FixedSizeCodec[Data any] struct {
+	// Size of each record in bytes.
+	Size   int
+	Encode func(data *Data, b []byte)
+	Decode func(b []byte) Data
+}
+
+type MmapDataOptions struct {
+	// Keep modifications in memory on top of the file. Otherwise Merge and Delete return ErrReadOnly.
+	Overlay bool
+}
+
+const mmapDataFilePattern = "archive-*.bin"
+
+// Creates factory of storages, each of which writes its initial data into separate file inside dir and maps it into memory.
+// Storages are read-only, unless Overlay option is set. Files are removed, when storages are released by the series.
+func NewMmapDataFactory[Data any, Index any](dir string, codec FixedSizeCodec[Data], opts MmapDataOptions) SeriesDataFactory[Data, Index] {
+	return func(
+		getIdx func(data *Data) Index,
+		idxCmp func(idx1, idx2 Index) int,
+		periodStart, periodEnd Index, data []Data,
+	) (SeriesData[Data, Index], error) {
+		file, err := os.CreateTemp(dir, mmapDataFilePattern)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to create archive file")
+		}
+		if err := file.Close(); err != nil {
+			return nil, errors.Wrap(err, "failed to create archive file")
+		}
+
+		path := file.Name()
+
+		if err := WriteMmapDataFile(path, codec, data); err != nil {
+			os.Remove(path)
+			return nil, err
+		}
+
+		s, err := OpenMmapData(path, getIdx, idxCmp, codec, opts)
+		if err != nil {
+			os.Remove(path)
+			return nil, err
+		}
+
+		s.owned = true
+
+		return s, nil
+	}
+}
+
+var _ SeriesDataFactory[int, int] = NewMmapDataFactory[int, int]("", FixedSizeCodec[int]{}, MmapDataOptions{})
+
+// Writes sorted data into file, which can be opened with OpenMmapData.
+func WriteMmapDataFile[Data any](path string, codec FixedSizeCodec[Data], data []Data) error {
+	b := make([]byte, codec.Size*len(data))
+
+	for i := range data {
+		codec.Encode(&data[i], b[i*codec.Size:(i+1)*codec.Size])
+	}
+
+	return replaceFile(path, b, true)
+}
+
+// Opens file of records sorted by index and maps it into memory. File must not be modified while it is open.
+func OpenMmapData[Data any, Index any](
+	path string,
+	getIdx func(data *Data) Index,
+	idxCmp func(idx1, idx2 Index) int,
+	codec FixedSizeCodec[Data],
+	opts MmapDataOptions,
+) (*MmapData[Data, Index], error) {
+	if codec.Size <= 0 {
+		return nil, errors.Errorf("invalid record size %v", codec.Size)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to open file %v", path)
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get size of file %v", path)
+	}
+
+	if info.Size()%int64(codec.Size) != 0 {
+		return nil, errors.Errorf("size of file %v is not multiple of record size %v: %v", path, codec.Size, info.Size())
+	}
+
+	s := &MmapData[Data, Index]{
+		path:   path,
+		getIdx: getIdx,
+		idxCmp: idxCmp,
+		codec:  codec,
+		opts:   opts,
+		file:   &mmapFile{count: int(info.Size() / int64(codec.Size))},
+	}
+
+	if s.file.count != 0 {
+		if s.file.b, err = mapFile(f, int(info.Size())); err != nil {
+			return nil, errors.Wrapf(err, "failed to map file %v", path)
+		}
+	}
+
+	if opts.Overlay {
+		s.overlay = &ArrayData[Data, Index]{getIdx: getIdx, idxCmp: idxCmp}
+	}
+
+	return s, nil
+}
+
+// Read-only storage backed by memory-mapped file of fixed-size records sorted by index.
+// Records are decoded on demand, so the file is never copied into the heap. If overlay is enabled,
+// modifications are kept in memory and hide records of the file within modified periods.
+type MmapData[Data any, Index any] struct {
+	path   string
+	getIdx func(data *Data) Index
+	idxCmp func(idx1, idx2 Index) int
+	codec  FixedSizeCodec[Data]
+	opts   MmapDataOptions
+
+	file *mmapFile
+	// File was created by NewMmapDataFactory, so it is removed together with storage.
+	owned bool
+
+	// Sorted non-overlapping closed periods, in which records of the file are hidden. Never modified in place.
+	masked  []PeriodBounds[Index]
+	overlay *ArrayData[Data, Index]
+}
+
+// Mapping of the file, which is shared by storage and its snapshots.
+type mmapFile struct {
+	b      []byte
+	count  int
+	closed bool
+}
+
+func (s *MmapData[Data, Index]) Path() string {
+	return s.path
+}
+
+// Unmaps the file. After that all methods of storage and its snapshots return ErrClosed,
+// and iteration over sequences returned before stops. Must not be called concurrently with other methods.
+func (s *MmapData[Data, Index]) Close() error {
+	if s.file.closed {
+		return nil
+	}
+
+	b := s.file.b
+	s.file.b, s.file.count, s.file.closed = nil, 0, true
+
+	if b == nil {
+		return nil
+	}
+
+	return errors.Wrapf(unmapFile(b), "failed to unmap file %v", s.path)
+}
+
+// Closes storage. File is removed only if it was created by NewMmapDataFactory - files opened with OpenMmapData are kept.
+func (s *MmapData[Data, Index]) Remove() error {
+	if !s.owned {
+		return nil
+	}
+
+	if err := s.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Remove(s.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return errors.Wrapf(err, "failed to remove file %v", s.path)
+	}
+
+	return nil
+}
+
+var _ RemovableSeriesData = &MmapData[int, int]{}
+
+func (s *MmapData[Data, Index]) checkOpen() error {
+	if s.file.closed {
+		return errors.Wrapf(ErrClosed, "file %v", s.path)
+	}
+
+	return nil
+}
+
+func (s *MmapData[Data, Index]) First(idx Index) (*Data, error) {
+	if err := s.checkOpen(); err != nil {
+		return nil, err
+	}
+
+	var first *Data
+	for v := range s.all(0, s.file.count, false) {
+		first = &v
+		break
+	}
+
+	if s.overlay == nil {
+		return first, nil
+	}
+
+	overlayFirst, err := s.overlay.First(idx)
+	if err != nil {
+		return nil, err
+	}
+
+	if first == nil || (overlayFirst != nil && s.idxCmp(s.getIdx(overlayFirst), s.getIdx(first)) < 0) {
+		return overlayFirst, nil
+	}
+
+	return first, nil
+}
+
+func (s *MmapData[Data, Index]) Last(idx Index) (*Data, error) {
+	if err := s.checkOpen(); err != nil {
+		return nil, err
+	}
+
+	var last *Data
+	for v := range s.all(0, s.file.count, true) {
+		last = &v
+		break
+	}
+
+	if s.overlay == nil {
+		return last, nil
+	}
+
+	overlayLast, err := s.overlay.Last(idx)
+	if err != nil {
+		return nil, err
+	}
+
+	if last == nil || (overlayLast != nil && s.idxCmp(s.getIdx(overlayLast), s.getIdx(last)) > 0) {
+		return overlayLast, nil
+	}
+
+	return last, nil
+}
+
+func (s *MmapData[Data, Index]) Get(periodStart, periodEnd Index) ([]Data, error) {
+	if err := s.checkOpen(); err != nil {
+		return nil, err
+	}
+
+	lo, hi := s.find(periodStart, periodEnd, false)
+	return s.collect(lo, hi, periodStart, periodEnd, false)
+}
+
+func (s *MmapData[Data, Index]) GetEndOpen(periodStart, periodEnd Index) ([]Data, error) {
+	if err := s.checkOpen(); err != nil {
+		return nil, err
+	}
+
+	lo, hi := s.find(periodStart, periodEnd, true)
+	return s.collect(lo, hi, periodStart, periodEnd, true)
+}
+
+func (s *MmapData[Data, Index]) collect(lo, hi int, periodStart, periodEnd Index, endOpen bool) ([]Data, error) {
+	res := slices.Collect(s.all(lo, hi, false))
+
+	if s.overlay == nil {
+		if res == nil {
+			return []Data{}, nil
+		}
+		return res, nil
+	}
+
+	return s.mergeSorted(res, s.overlay.get(periodStart, periodEnd, endOpen)), nil
+}
+
+func (s *MmapData[Data, Index]) Merge(data []Data) error {
+	if err := s.checkOpen(); err != nil {
+		return err
+	}
+	if len(data) == 0 {
+		return nil
+	}
+	if s.overlay == nil {
+		return errors.Wrapf(ErrReadOnly, "file %v", s.path)
+	}
+
+	s.mask(s.getIdx(&data[0]), s.getIdx(&data[len(data)-1]))
+
+	return s.overlay.Merge(data)
+}
+
+func (s *MmapData[Data, Index]) Delete(periodStart, periodEnd Index) error {
+	if err := s.checkOpen(); err != nil {
+		return err
+	}
+
+	if s.overlay == nil {
+		lo, hi := s.find(periodStart, periodEnd, false)
+		if lo == hi {
+			return nil
+		}
+
+		return errors.Wrapf(ErrReadOnly, "file %v", s.path)
+	}
+
+	s.mask(periodStart, periodEnd)
+
+	return s.overlay.Delete(periodStart, periodEnd)
+}
+
+func (s *MmapData[Data, Index]) All(periodStart, periodEnd Index) (iter.Seq[Data], error) {
+	if err := s.checkOpen(); err != nil {
+		return nil, err
+	}
+
+	if s.overlay != nil {
+		return s.iterateCollected(periodStart, periodEnd, false)
+	}
+
+	lo, hi := s.find(periodStart, periodEnd, false)
+
+	return s.all(lo, hi, false), nil
+}
+
+func (s *MmapData[Data, Index]) Backward(periodStart, periodEnd Index) (iter.Seq[Data], error) {
+	if err := s.checkOpen(); err != nil {
+		return nil, err
+	}
+
+	if s.overlay != nil {
+		return s.iterateCollected(periodStart, periodEnd, true)
+	}
+
+	lo, hi := s.find(periodStart, periodEnd, false)
+
+	return s.all(lo, hi, true), nil
+}
+
+var _ IterableSeriesData[int, int] = &MmapData[int, int]{}
+
+// File is never modified and neither are masked periods, so snapshot shares them. Snapshot is closed together with storage.
+// Snapshot never removes the file.
+func (s *MmapData[Data, Index]) Snapshot() (SeriesData[Data, Index], error) {
+	if err := s.checkOpen(); err != nil {
+		return nil, err
+	}
+
+	snapshot := *s
+	snapshot.owned = false
+
+	if s.overlay != nil {
+		overlay, err := s.overlay.Snapshot()
+		if err != nil {
+			return nil, err
+		}
+
+		snapshot.overlay = overlay.(*ArrayData[Data, Index])
+	}
+
+	return &snapshot, nil
+}
+
+var _ SnapshotableSeriesData[int, int] = &MmapData[int, int]{}
+
+func (s *MmapData[Data, Index]) String() string {
+	return s.path
+}
+
+func (s *MmapData[Data, Index]) iterateCollected(periodStart, periodEnd Index, backward bool) (iter.Seq[Data], error) {
+	data, err := s.Get(periodStart, periodEnd)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(yield func(Data) bool) {
+		for n := range data {
+			i := n
+			if backward {
+				i = len(data) - 1 - n
+			}
+
+			if !yield(data[i]) {
+				return
+			}
+		}
+	}, nil
+}
+
+func (s *MmapData[Data, Index]) record(i int) Data {
+	return s.codec.Decode(s.file.b[i*s.codec.Size : (i+1)*s.codec.Size])
+}
+
+// Returns range [ lo ; hi ) of records within the period.
+func (s *MmapData[Data, Index]) find(periodStart, periodEnd Index, endOpen bool) (lo, hi int) {
+	lo = sort.Search(s.file.count, func(i int) bool {
+		v := s.record(i)
+		return s.idxCmp(s.getIdx(&v), periodStart) >= 0
+	})
+
+	hi = lo + sort.Search(s.file.count-lo, func(i int) bool {
+		v := s.record(lo + i)
+		cmp := s.idxCmp(s.getIdx(&v), periodEnd)
+		return cmp > 0 || (endOpen && cmp == 0)
+	})
+
+	return lo, hi
+}
+
+// Returns sequence of records in range [ lo ; hi ), which are not masked. Sequence stops, if the file is closed.
+func (s *MmapData[Data, Index]) all(lo, hi int, backward bool) iter.Seq[Data] {
+	return func(yield func(Data) bool) {
+		for n := 0; n < hi-lo && !s.file.closed; n++ {
+			i := lo + n
+			if backward {
+				i = hi - 1 - n
+			}
+
+			v := s.record(i)
+			if s.isMasked(s.getIdx(&v)) {
+				continue
+			}
+
+			if !yield(v) {
+				return
+			}
+		}
+	}
+}
+
+func (s *MmapData[Data, Index]) isMasked(idx Index) bool {
+	i := sort.Search(len(s.masked), func(i int) bool {
+		return s.idxCmp(s.masked[i].PeriodEnd, idx) >= 0
+	})
+
+	return i < len(s.masked) && s.idxCmp(s.masked[i].PeriodStart, idx) <= 0
+}
+
+// Hides records of the file within the period.
+func (s *MmapData[Data, Index]) mask(periodStart, periodEnd Index) {
+	lo, hi := findChunks(len(s.masked), s.idxCmp, periodStart, periodEnd, func(i int) (first, last Index) {
+		return s.masked[i].PeriodStart, s.masked[i].PeriodEnd
+	})
+
+	if lo < hi {
+		if s.idxCmp(s.masked[lo].PeriodStart, periodStart) < 0 {
+			periodStart = s.masked[lo].PeriodStart
+		}
+		if s.idxCmp(s.masked[hi-1].PeriodEnd, periodEnd) > 0 {
+			periodEnd = s.masked[hi-1].PeriodEnd
+		}
+	}
+
+	s.masked = slices.Concat(s.masked[:lo], []PeriodBounds[Index]{{PeriodStart: periodStart, PeriodEnd: periodEnd}}, s.masked[hi:])
+}
+
+// Merges records of the file with data of overlay. They never have the same index, because overlay data lies within masked periods.
+func (s *MmapData[Data, Index]) mergeSorted(records, overlayData []Data) []Data {
+	res := make([]Data, 0, len(records)+len(overlayData))
+
+	for len(records) != 0 && len(overlayData) != 0 {
+		if s.idxCmp(s.getIdx(&records[0]), s.getIdx(&overlayData[0])) < 0 {
+			res = append(res, records[0])
+			records = records[1:]
+		} else {
+			res = append(res, overlayData[0])
+			overlayData = overlayData[1:]
+		}
+	}
+
+	res = append(res, records...)
+	res = append(res, overlayData...)
+
+	return res
+}
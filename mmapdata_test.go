@@ -0,0 +1,262 @@
+//go:build unix
+
+package sparse_test
+
+import (
+	"encoding/binary"
+	"math"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/nnikolash/go-sparse"
+	"github.com/nnikolash/go-sparse/sparsetest"
+	"github.com/stretchr/testify/require"
+)
+
+var itemFixedSizeCodec = sparse.FixedSizeCodec[sparsetest.Item]{
+	Size: 16,
+	Encode: func(item *sparsetest.Item, b []byte) {
+		binary.LittleEndian.PutUint64(b, uint64(item.Idx))
+		binary.LittleEndian.PutUint64(b[8:], math.Float64bits(item.Val))
+	},
+	Decode: func(b []byte) sparsetest.Item {
+		return sparsetest.Item{
+			Idx: int(binary.LittleEndian.Uint64(b)),
+			Val: math.Float64frombits(binary.LittleEndian.Uint64(b[8:])),
+		}
+	},
+}
+
+func openItemMmapData(t *testing.T, opts sparse.MmapDataOptions, data ...sparsetest.Item) *sparse.MmapData[sparsetest.Item, int] {
+	path := filepath.Join(t.TempDir(), "archive.bin")
+	require.NoError(t, sparse.WriteMmapDataFile(path, itemFixedSizeCodec, data))
+
+	s, err := sparse.OpenMmapData(path, sparsetest.ItemIdx, sparsetest.ItemIdxCmp, itemFixedSizeCodec, opts)
+	require.NoError(t, err)
+	t.Cleanup(func() { require.NoError(t, s.Close()) })
+
+	return s
+}
+
+func TestMmapData_OverlayConformance(t *testing.T) {
+	t.Parallel()
+
+	sparsetest.RunSeriesDataConformance(t, func(
+		getIdx func(data *sparsetest.Item) int,
+		idxCmp func(idx1, idx2 int) int,
+		periodStart, periodEnd int, data []sparsetest.Item,
+	) (sparse.SeriesData[sparsetest.Item, int], error) {
+		return openItemMmapData(t, sparse.MmapDataOptions{Overlay: true}, data...), nil
+	}, sparsetest.ItemFixture())
+}
+
+func TestMmapData_ReadOnly(t *testing.T) {
+	t.Parallel()
+
+	s := openItemMmapData(t, sparse.MmapDataOptions{}, sparsetest.Items(1, 1, 3, 5, 7)...)
+
+	require.Equal(t, sparsetest.Items(1, 3, 5), must2(s.Get(2, 6)))
+	require.Equal(t, sparsetest.Items(1, 3), must2(s.GetEndOpen(3, 5)))
+	require.Equal(t, []sparsetest.Item{}, must2(s.Get(8, 10)))
+	require.Equal(t, item(1, 1), *must2(s.First(0)))
+	require.Equal(t, item(7, 7), *must2(s.Last(10)))
+	require.Equal(t, sparsetest.Items(1, 5, 3), slices.Collect(must2(s.Backward(2, 6))))
+
+	require.ErrorIs(t, s.Merge(sparsetest.Items(1, 2)), sparse.ErrReadOnly)
+	require.ErrorIs(t, s.Delete(2, 4), sparse.ErrReadOnly)
+	require.NoError(t, s.Delete(8, 10))
+	require.Equal(t, sparsetest.Items(1, 1, 3, 5, 7), must2(s.Get(0, 10)))
+}
+
+func TestMmapData_Empty(t *testing.T) {
+	t.Parallel()
+
+	s := openItemMmapData(t, sparse.MmapDataOptions{})
+
+	require.Nil(t, must2(s.First(0)))
+	require.Equal(t, []sparsetest.Item{}, must2(s.Get(0, 10)))
+}
+
+func TestMmapData_InvalidFile(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "archive.bin")
+	require.NoError(t, os.WriteFile(path, make([]byte, 20), 0o644))
+
+	_, err := sparse.OpenMmapData(path, sparsetest.ItemIdx, sparsetest.ItemIdxCmp, itemFixedSizeCodec, sparse.MmapDataOptions{})
+	require.Error(t, err)
+}
+
+func TestMmapData_OverlayMatchesArrayData(t *testing.T) {
+	t.Parallel()
+
+	var initial []sparsetest.Item
+	for idx := 0; idx < 120; idx += 1 + rand.IntN(3) {
+		initial = append(initial, item(idx, rand.NormFloat64()))
+	}
+
+	s := openItemMmapData(t, sparse.MmapDataOptions{Overlay: true}, initial...)
+	arrayData := must2(sparse.NewArrayData(sparsetest.ItemIdx, sparsetest.ItemIdxCmp, 0, 0, slices.Clone(initial)))
+
+	for i := 0; i < 500; i++ {
+		start := rand.IntN(100)
+		end := start + rand.IntN(20)
+
+		if rand.IntN(3) == 0 {
+			require.NoError(t, s.Delete(start, end))
+			require.NoError(t, arrayData.Delete(start, end))
+		} else {
+			var data []sparsetest.Item
+			for idx := start; idx <= end; idx++ {
+				if idx == start || idx == end || rand.IntN(2) == 0 {
+					data = append(data, item(idx, rand.NormFloat64()))
+				}
+			}
+
+			require.NoError(t, s.Merge(data))
+			require.NoError(t, arrayData.Merge(data))
+		}
+
+		require.Equal(t, must2(arrayData.Get(0, 200)), must2(s.Get(0, 200)), "step %v", i)
+		require.Equal(t, must2(arrayData.GetEndOpen(30, 60)), must2(s.GetEndOpen(30, 60)), "step %v", i)
+		require.Equal(t, must2(arrayData.First(0)), must2(s.First(0)), "step %v", i)
+		require.Equal(t, must2(arrayData.Last(200)), must2(s.Last(200)), "step %v", i)
+	}
+}
+
+func TestMmapData_Series(t *testing.T) {
+	t.Parallel()
+
+	archive := openItemMmapData(t, sparse.MmapDataOptions{Overlay: true}, sparsetest.Items(1, 10, 20, 30, 40)...)
+
+	series := itemSparseSeries(sparse.NewArrayData)
+	series.SetValidateOnChange(true)
+
+	require.NoError(t, series.Restore(&sparse.SeriesState[sparsetest.Item, int]{
+		Segments: []*sparse.SeriesSegmentFields[sparsetest.Item, int]{{
+			PeriodBounds: sparse.PeriodBounds[int]{PeriodStart: 0, PeriodEnd: 50},
+			Data:         archive,
+		}},
+	}))
+
+	require.NoError(t, series.AddPeriod(15, 25, sparsetest.Items(2, 25)))
+	require.NoError(t, series.AddPeriod(50, 60, sparsetest.Items(1, 55)))
+
+	res, err := series.Get(0, 60)
+	require.NoError(t, err)
+	require.Equal(t, []sparsetest.Item{item(10, 10), item(25, 50), item(30, 30), item(40, 40), item(55, 55)}, res)
+
+	// File itself is not changed
+	reopened, err := sparse.OpenMmapData(archive.Path(), sparsetest.ItemIdx, sparsetest.ItemIdxCmp, itemFixedSizeCodec, sparse.MmapDataOptions{})
+	require.NoError(t, err)
+	defer reopened.Close()
+	require.Equal(t, sparsetest.Items(1, 10, 20, 30, 40), must2(reopened.Get(0, 50)))
+}
+
+func TestMmapData_FactoryConformance(t *testing.T) {
+	t.Parallel()
+
+	sparsetest.RunSeriesDataConformance(t, sparse.NewMmapDataFactory[sparsetest.Item, int](t.TempDir(), itemFixedSizeCodec, sparse.MmapDataOptions{Overlay: true}), sparsetest.ItemFixture())
+}
+
+func TestMmapData_FactorySeries(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+
+	series := itemSparseSeries(sparse.NewMmapDataFactory[sparsetest.Item, int](dir, itemFixedSizeCodec, sparse.MmapDataOptions{}))
+	series.SetValidateOnChange(true)
+
+	require.NoError(t, series.AddPeriod(10, 20, sparsetest.Items(1, 10, 20)))
+	require.NoError(t, series.AddPeriod(30, 40, sparsetest.Items(1, 35)))
+	require.NoError(t, series.AddPeriod(50, 60, sparsetest.Items(1, 55)))
+	require.Equal(t, 3, countFiles(t, dir))
+
+	// Storages are read-only, so data can only be added as new segments
+	require.ErrorIs(t, series.AddPeriod(15, 35, sparsetest.Items(2, 25)), sparse.ErrReadOnly)
+	require.Equal(t, 3, countFiles(t, dir))
+	require.Equal(t, sparsetest.Items(1, 10, 20), must2(series.Get(10, 20)))
+	require.Equal(t, sparsetest.Items(1, 35), must2(series.Get(30, 40)))
+
+	require.NoError(t, series.DeletePeriod(30, 60))
+	require.Equal(t, 1, countFiles(t, dir))
+	require.Equal(t, sparsetest.Items(1, 10, 20), must2(series.Get(10, 20)))
+}
+
+func TestMmapData_Closed(t *testing.T) {
+	t.Parallel()
+
+	for name, opts := range map[string]sparse.MmapDataOptions{
+		"read-only": {},
+		"overlay":   {Overlay: true},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			s := openItemMmapData(t, opts, sparsetest.Items(1, 1, 3, 5, 7)...)
+
+			snapshot, err := s.Snapshot()
+			require.NoError(t, err)
+
+			all, err := s.All(0, 10)
+			require.NoError(t, err)
+
+			require.NoError(t, s.Close())
+			require.NoError(t, s.Close())
+
+			for _, data := range []sparse.SeriesData[sparsetest.Item, int]{s, snapshot} {
+				_, err := data.Get(0, 10)
+				require.ErrorIs(t, err, sparse.ErrClosed)
+				_, err = data.GetEndOpen(0, 10)
+				require.ErrorIs(t, err, sparse.ErrClosed)
+				_, err = data.First(0)
+				require.ErrorIs(t, err, sparse.ErrClosed)
+				_, err = data.Last(10)
+				require.ErrorIs(t, err, sparse.ErrClosed)
+				require.ErrorIs(t, data.Merge(sparsetest.Items(1, 2)), sparse.ErrClosed)
+				require.ErrorIs(t, data.Delete(0, 10), sparse.ErrClosed)
+
+				iterable := data.(sparse.IterableSeriesData[sparsetest.Item, int])
+				_, err = iterable.All(0, 10)
+				require.ErrorIs(t, err, sparse.ErrClosed)
+				_, err = iterable.Backward(0, 10)
+				require.ErrorIs(t, err, sparse.ErrClosed)
+
+				_, err = data.(sparse.SnapshotableSeriesData[sparsetest.Item, int]).Snapshot()
+				require.ErrorIs(t, err, sparse.ErrClosed)
+			}
+
+			if !opts.Overlay {
+				require.Empty(t, slices.Collect(all))
+			}
+		})
+	}
+}
+
+func TestMmapData_FactoryAsColdStorage(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+
+	series := itemSparseSeries(sparse.NewArrayData)
+	require.NoError(t, series.SetTiering(&sparse.TieringPolicy[sparsetest.Item, int]{
+		HotSegments: 1,
+		Cold:        sparse.NewMmapDataFactory[sparsetest.Item, int](dir, itemFixedSizeCodec, sparse.MmapDataOptions{Overlay: true}),
+	}))
+
+	require.NoError(t, series.AddPeriod(10, 20, sparsetest.Items(1, 10, 20)))
+	require.NoError(t, series.AddPeriod(30, 40, sparsetest.Items(1, 35)))
+	require.NoError(t, series.AddPeriod(50, 60, sparsetest.Items(1, 55)))
+	require.Equal(t, 2, countFiles(t, dir))
+
+	require.NoError(t, series.AddPeriod(15, 18, sparsetest.Items(3, 16)))
+	require.Equal(t, []sparsetest.Item{item(10, 10), item(16, 48), item(20, 20)}, must2(series.Get(10, 20)))
+	require.Equal(t, sparsetest.Items(1, 35), must2(series.Get(30, 40)))
+	require.Equal(t, 2, countFiles(t, dir))
+
+	require.NoError(t, series.DeletePeriod(0, 100))
+	require.Equal(t, 0, countFiles(t, dir))
+}
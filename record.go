@@ -0,0 +1,167 @@
+package sparse
+
+import (
+	"bufio"
+	"encoding/binary"
+	"hash/crc32"
+	"io"
+	"os"
+
+	"github.com/pkg/errors"
+)
+
+// Log files consist of records: [ payload length : 4 ][ payload checksum : 4 ][ payload ].
+
+const recordHeaderSize = 8
+
+var recordChecksumTable = crc32.MakeTable(crc32.Castagnoli)
+
+func appendRecord(b []byte, payload []byte) []byte {
+	b = binary.LittleEndian.AppendUint32(b, uint32(len(payload)))
+	b = binary.LittleEndian.AppendUint32(b, crc32.Checksum(payload, recordChecksumTable))
+	return append(b, payload...)
+}
+
+// Reads records one by one until the end of file or the first torn or corrupted record.
+// Returns size of the valid part of the file.
+func readRecords(path string, f func(offset int64, payload []byte) error) (validSize int64, _ error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, errors.Wrapf(err, "failed to open log %v", path)
+	}
+	defer file.Close()
+
+	stat, err := file.Stat()
+	if err != nil {
+		return 0, errors.Wrapf(err, "failed to stat log %v", path)
+	}
+
+	r := bufio.NewReader(file)
+	header := make([]byte, recordHeaderSize)
+
+	for {
+		if _, err := io.ReadFull(r, header); err != nil {
+			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+				return validSize, nil
+			}
+			return validSize, errors.Wrapf(err, "failed to read log %v", path)
+		}
+
+		size := int64(binary.LittleEndian.Uint32(header))
+		if validSize+recordHeaderSize+size > stat.Size() {
+			return validSize, nil
+		}
+
+		payload := make([]byte, size)
+		if _, err := io.ReadFull(r, payload); err != nil {
+			if errors.Is(err, io.ErrUnexpectedEOF) {
+				return validSize, nil
+			}
+			return validSize, errors.Wrapf(err, "failed to read log %v", path)
+		}
+
+		if crc32.Checksum(payload, recordChecksumTable) != binary.LittleEndian.Uint32(header[4:]) {
+			return validSize, nil
+		}
+
+		if err := f(validSize, payload); err != nil {
+			return validSize, err
+		}
+
+		validSize += recordHeaderSize + size
+	}
+}
+
+// Reads records and truncates the file after the last valid record.
+func recoverRecords(path string, f func(offset int64, payload []byte) error) (validSize int64, _ error) {
+	validSize, err := readRecords(path, f)
+	if err != nil {
+		return 0, err
+	}
+
+	stat, err := os.Stat(path)
+	if err != nil {
+		return 0, errors.Wrapf(err, "failed to stat log %v", path)
+	}
+
+	if stat.Size() > validSize {
+		if err := os.Truncate(path, validSize); err != nil {
+			return 0, errors.Wrapf(err, "failed to truncate log %v", path)
+		}
+	}
+
+	return validSize, nil
+}
+
+func readRecordAt(path string, offset int64) ([]byte, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to open log %v", path)
+	}
+	defer file.Close()
+
+	header := make([]byte, recordHeaderSize)
+	if _, err := file.ReadAt(header, offset); err != nil {
+		return nil, errors.Wrapf(err, "failed to read record header at %v in log %v", offset, path)
+	}
+
+	payload := make([]byte, binary.LittleEndian.Uint32(header))
+	if _, err := file.ReadAt(payload, offset+recordHeaderSize); err != nil {
+		return nil, errors.Wrapf(err, "failed to read record at %v in log %v", offset, path)
+	}
+
+	if crc32.Checksum(payload, recordChecksumTable) != binary.LittleEndian.Uint32(header[4:]) {
+		return nil, errors.Errorf("checksum mismatch of record at %v in log %v", offset, path)
+	}
+
+	return payload, nil
+}
+
+func appendToFile(path string, b []byte, sync bool) error {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return errors.Wrapf(err, "failed to open log %v", path)
+	}
+
+	if _, err := file.Write(b); err != nil {
+		file.Close()
+		return errors.Wrapf(err, "failed to write log %v", path)
+	}
+
+	if sync {
+		if err := file.Sync(); err != nil {
+			file.Close()
+			return errors.Wrapf(err, "failed to sync log %v", path)
+		}
+	}
+
+	return errors.Wrapf(file.Close(), "failed to close log %v", path)
+}
+
+// Atomically replaces content of the file.
+func replaceFile(path string, b []byte, sync bool) error {
+	tmpPath := path + ".tmp"
+
+	file, err := os.OpenFile(tmpPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return errors.Wrapf(err, "failed to create file %v", tmpPath)
+	}
+
+	if _, err := file.Write(b); err != nil {
+		file.Close()
+		return errors.Wrapf(err, "failed to write file %v", tmpPath)
+	}
+
+	if sync {
+		if err := file.Sync(); err != nil {
+			file.Close()
+			return errors.Wrapf(err, "failed to sync file %v", tmpPath)
+		}
+	}
+
+	if err := file.Close(); err != nil {
+		return errors.Wrapf(err, "failed to close file %v", tmpPath)
+	}
+
+	return errors.Wrapf(os.Rename(tmpPath, path), "failed to replace file %v", path)
+}
@@ -0,0 +1,100 @@
+package sparse
+
+import "github.com/pkg/errors"
+
+// Describes which part of series must be kept. Older segments and their data are removed.
+// Zero values of fields mean no limit.
+type RetentionPolicy[Index any] struct {
+	// Returns the oldest index to keep, given the end of the latest segment.
+	MinIndex func(lastIdx Index) Index
+	// Maximum number of latest segments to keep.
+	MaxSegments int
+}
+
+func RetainPeriod[Index any](minIndex func(lastIdx Index) Index) *RetentionPolicy[Index] {
+	return &RetentionPolicy[Index]{MinIndex: minIndex}
+}
+
+func RetainSegments[Index any](maxSegments int) *RetentionPolicy[Index] {
+	return &RetentionPolicy[Index]{MaxSegments: maxSegments}
+}
+
+// Sets retention policy, which is enforced immediately and after each addition of data.
+// Nil disables retention.
+func (s *Series[Data, Index]) SetRetention(policy *RetentionPolicy[Index]) error {
+	if policy != nil && policy.MaxSegments < 0 {
+		return errors.Errorf("invalid retention max segments: %v", policy.MaxSegments)
+	}
+
+	s.retention = policy
+
+	return s.modify(func() error {
+		if err := s.applyRetention(); err != nil {
+			return err
+		}
+
+		if err := s.applyTiering(); err != nil {
+			return err
+		}
+
+		return s.validateAfterChange()
+	})
+}
+
+func (s *Series[Data, Index]) applyRetention() error {
+	if s.retention == nil || s.segments.Len() == 0 {
+		return nil
+	}
+
+	if s.retention.MinIndex != nil {
+		minIdx := s.retention.MinIndex(s.segments.At(s.segments.Len() - 1).PeriodEnd)
+		if err := s.deleteBefore(minIdx); err != nil {
+			return err
+		}
+	}
+
+	if s.retention.MaxSegments > 0 && s.segments.Len() > s.retention.MaxSegments {
+		if err := s.deleteFirstSegments(s.segments.Len() - s.retention.MaxSegments); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Removes everything before t.
+func (s *Series[Data, Index]) deleteBefore(t Index) error {
+	segmentIdx, contains := s.findSegmentWhichStartsBeforeOrAt(t, false)
+	if segmentIdx == -1 {
+		return nil
+	}
+
+	if !contains {
+		return s.deleteFirstSegments(segmentIdx + 1)
+	}
+
+	if err := s.deleteFirstSegments(segmentIdx); err != nil {
+		return err
+	}
+
+	firstSegment := s.segments.At(0)
+	if s.idxCmp(firstSegment.PeriodStart, t) < 0 {
+		if err := s.backupSegmentData(firstSegment, firstSegment.PeriodStart, t); err != nil {
+			return err
+		}
+		if err := firstSegment.cutStart(t); err != nil {
+			return err
+		}
+
+		s.segments.Replace(0, 1, firstSegment)
+	}
+
+	return nil
+}
+
+// Storages of removed segments are released instead of clearing them.
+func (s *Series[Data, Index]) deleteFirstSegments(count int) error {
+	s.segments.Replace(0, count)
+
+	return nil
+}
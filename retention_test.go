@@ -0,0 +1,88 @@
+package sparse_test
+
+import (
+	"testing"
+
+	"github.com/nnikolash/go-sparse"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSparseSeries_RetainPeriod(t *testing.T) {
+	t.Parallel()
+
+	series := intSparseSeries()
+
+	err := series.SetRetention(sparse.RetainPeriod(func(lastIdx int) int { return lastIdx - 50 }))
+	require.NoError(t, err)
+
+	err = series.AddData([]int{10, 20, 30})
+	require.NoError(t, err)
+	err = series.AddData([]int{50, 60})
+	require.NoError(t, err)
+	println(0, series.SegmentsString())
+	require.Len(t, series.Segments(), 2)
+
+	err = series.AddData([]int{70, 80})
+	require.NoError(t, err)
+	println(0, series.SegmentsString())
+	require.Len(t, series.Segments(), 3)
+	require.Equal(t, 30, series.Segments()[0].PeriodStart)
+	res, err := series.Get(30, 30)
+	require.NoError(t, err)
+	require.Equal(t, []int{30}, res)
+	_, err = series.Get(20, 30)
+	require.Error(t, err)
+
+	err = series.AddData([]int{120})
+	require.NoError(t, err)
+	println(0, series.SegmentsString())
+	require.Len(t, series.Segments(), 2)
+	require.Equal(t, 70, series.Segments()[0].PeriodStart)
+	res, err = series.Get(70, 80)
+	require.NoError(t, err)
+	require.Equal(t, []int{70, 80}, res)
+
+	err = series.AddData([]int{200})
+	require.NoError(t, err)
+	println(0, series.SegmentsString())
+	require.Len(t, series.Segments(), 1)
+}
+
+func TestSparseSeries_RetainSegments(t *testing.T) {
+	t.Parallel()
+
+	series := intSparseSeries()
+
+	err := series.AddData([]int{10, 20})
+	require.NoError(t, err)
+	err = series.AddData([]int{40, 50})
+	require.NoError(t, err)
+	err = series.AddData([]int{70, 80})
+	require.NoError(t, err)
+
+	err = series.SetRetention(sparse.RetainSegments[int](2))
+	require.NoError(t, err)
+	println(0, series.SegmentsString())
+	require.Len(t, series.Segments(), 2)
+	_, err = series.Get(10, 20)
+	require.Error(t, err)
+
+	err = series.AddData([]int{100})
+	require.NoError(t, err)
+	println(0, series.SegmentsString())
+	require.Len(t, series.Segments(), 2)
+	res, err := series.Get(70, 80)
+	require.NoError(t, err)
+	require.Equal(t, []int{70, 80}, res)
+
+	err = series.AddData([]int{81, 99})
+	require.NoError(t, err)
+	println(0, series.SegmentsString())
+	require.Len(t, series.Segments(), 1)
+
+	err = series.SetRetention(nil)
+	require.NoError(t, err)
+	err = series.AddData([]int{0})
+	require.NoError(t, err)
+	require.Len(t, series.Segments(), 2)
+}
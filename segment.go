@@ -1,6 +1,8 @@
 package sparse
 
 import (
+	"fmt"
+
 	"github.com/pkg/errors"
 )
 
@@ -84,6 +86,30 @@ func (e *SeriesSegment[Data, Index]) Last() (*Data, error) {
 	return e.Data.Last(e.PeriodEnd)
 }
 
+// Returns all items, which have the same index as the first item.
+func (e *SeriesSegment[Data, Index]) FirstGroup() ([]Data, error) {
+	first, err := e.First()
+	if err != nil || first == nil {
+		return nil, err
+	}
+
+	idx := e.getIdx(first)
+
+	return e.Data.Get(idx, idx)
+}
+
+// Returns all items, which have the same index as the last item.
+func (e *SeriesSegment[Data, Index]) LastGroup() ([]Data, error) {
+	last, err := e.Last()
+	if err != nil || last == nil {
+		return nil, err
+	}
+
+	idx := e.getIdx(last)
+
+	return e.Data.Get(idx, idx)
+}
+
 func (e *SeriesSegment[Data, Index]) ContainsPoint(t Index) bool {
 	if e.Data == nil {
 		return false
@@ -116,6 +142,11 @@ func (e *SeriesSegment[Data, Index]) MergeData(data []Data) error {
 }
 
 func (e *SeriesSegment[Data, Index]) MergePeriod(periodStart, periodEnd Index, data []Data) error {
+	return e.mergePeriod(periodStart, periodEnd, data, false)
+}
+
+// If replace is set, existing data of the period is removed even from storage, which has its own merge policy.
+func (e *SeriesSegment[Data, Index]) mergePeriod(periodStart, periodEnd Index, data []Data, replace bool) error {
 	if err := e.validateDataBounds(periodStart, periodEnd, data); err != nil {
 		return err
 	}
@@ -138,13 +169,145 @@ func (e *SeriesSegment[Data, Index]) MergePeriod(periodStart, periodEnd Index, d
 		return err
 	}
 
+	// Provided data replaces all old data of the period, not only data within its own bounds.
+	// Storage with merge policy combines provided data with old data itself.
+	mergesData := e.storageMergesData()
+	if !e.Empty && (replace && mergesData || !mergesData && !e.dataCoversPeriod(periodStart, periodEnd, data)) {
+		if err := e.Data.Delete(periodStart, periodEnd); err != nil {
+			return err
+		}
+	}
+
 	if err := e.Data.Merge(data); err != nil {
 		return err
 	}
 
-	e.PeriodStart = e.getSmallerIndex(e.PeriodStart, periodStart)
-	e.PeriodEnd = e.getBiggerIndex(e.PeriodEnd, periodEnd)
-	e.Empty = e.Empty && len(data) == 0
+	if err := e.setBounds(e.getSmallerIndex(e.PeriodStart, periodStart), e.getBiggerIndex(e.PeriodEnd, periodEnd)); err != nil {
+		return err
+	}
+
+	if len(data) != 0 {
+		e.Empty = false
+		return nil
+	}
+
+	return e.updateEmpty()
+}
+
+func (e *SeriesSegment[Data, Index]) storageMergesData() bool {
+	storage, ok := e.Data.(MergingSeriesData[Data])
+	return ok && !storage.MergePolicy().isOverwrite()
+}
+
+func (e *SeriesSegment[Data, Index]) dataCoversPeriod(periodStart, periodEnd Index, data []Data) bool {
+	if len(data) == 0 {
+		return false
+	}
+
+	return e.idxCmp(e.getIdx(&data[0]), periodStart) == 0 && e.idxCmp(e.getIdx(&data[len(data)-1]), periodEnd) == 0
+}
+
+// Keeps only [ periodStart ; PeriodEnd ] part of the segment.
+func (e *SeriesSegment[Data, Index]) cutStart(periodStart Index) error {
+	data, err := e.Data.GetEndOpen(e.PeriodStart, periodStart)
+	if err != nil {
+		return err
+	}
+
+	if err := e.setBounds(periodStart, e.PeriodEnd); err != nil {
+		return err
+	}
+
+	if len(data) != 0 {
+		if err := e.Data.Delete(e.getIdx(&data[0]), e.getIdx(&data[len(data)-1])); err != nil {
+			return err
+		}
+	}
+
+	return e.updateEmpty()
+}
+
+// Removes data within [ PeriodStart ; periodEnd ]. Index following periodEnd is unknown,
+// so the segment starts at its first remaining item, or shrinks to its end if no data remains.
+// E.g. end part of empty segment [ 0 ; 100 ], from which [ 1 ; 99 ] is deleted, becomes [ 100 ; 100 ].
+func (e *SeriesSegment[Data, Index]) deleteStart(periodEnd Index) error {
+	data, err := e.Data.Get(periodEnd, e.PeriodEnd)
+	if err != nil {
+		return err
+	}
+
+	periodStart := e.PeriodEnd
+	for i := range data {
+		if idx := e.getIdx(&data[i]); e.idxCmp(idx, periodEnd) > 0 {
+			periodStart = idx
+			break
+		}
+	}
+
+	// Bounds are changed first, so storage, which persists them, never keeps bounds of deleted period
+	deletedStart := e.PeriodStart
+	if err := e.setBounds(periodStart, e.PeriodEnd); err != nil {
+		return err
+	}
+
+	if err := e.Data.Delete(deletedStart, periodEnd); err != nil {
+		return err
+	}
+
+	return e.updateEmpty()
+}
+
+// Removes data within [ periodStart ; PeriodEnd ]. Index preceding periodStart is unknown,
+// so the segment ends at its last remaining item, or shrinks to its start if no data remains.
+// E.g. start part of empty segment [ 0 ; 100 ], from which [ 1 ; 99 ] is deleted, becomes [ 0 ; 0 ].
+func (e *SeriesSegment[Data, Index]) deleteEnd(periodStart Index) error {
+	data, err := e.Data.GetEndOpen(e.PeriodStart, periodStart)
+	if err != nil {
+		return err
+	}
+
+	periodEnd := e.PeriodStart
+	if len(data) != 0 {
+		periodEnd = e.getIdx(&data[len(data)-1])
+	}
+
+	deletedEnd := e.PeriodEnd
+	if err := e.setBounds(e.PeriodStart, periodEnd); err != nil {
+		return err
+	}
+
+	if err := e.Data.Delete(periodStart, deletedEnd); err != nil {
+		return err
+	}
+
+	return e.updateEmpty()
+}
+
+// Changes bounds of the segment and lets storage persist them.
+func (e *SeriesSegment[Data, Index]) setBounds(periodStart, periodEnd Index) error {
+	if storage, ok := e.Data.(BoundedSeriesData[Index]); ok {
+		if err := storage.SetBounds(periodStart, periodEnd); err != nil {
+			return err
+		}
+	}
+
+	e.PeriodStart = periodStart
+	e.PeriodEnd = periodEnd
+
+	return nil
+}
+
+func (e *SeriesSegment[Data, Index]) updateEmpty() error {
+	if e.Empty {
+		return nil
+	}
+
+	data, err := e.Data.Get(e.PeriodStart, e.PeriodEnd)
+	if err != nil {
+		return err
+	}
+
+	e.Empty = len(data) == 0
 
 	return nil
 }
@@ -213,24 +376,93 @@ func (e *SeriesSegment[Data, Index]) Restore(f *SeriesSegmentFields[Data, Index]
 	e.SeriesSegmentFields = *f
 }
 
-// func (e *SeriesEntry[Data, Index]) Validate() error {
-// 	if e.Data == nil {
-// 		return errors.New("data storage is not initialized")
-// 	}
+// Checks that segment bounds are correct, its data is sorted and lies within the bounds, and Empty flag matches the data.
+func (e *SeriesSegment[Data, Index]) Validate() error {
+	return e.validateData(true)
+}
+
+// If full is not set, only the first and the last items are checked, so storage is not read entirely.
+func (e *SeriesSegment[Data, Index]) validateData(full bool) error {
+	if err := e.validate(full); err != nil {
+		return errors.WithStack(&StorageCorruptedError[Index]{PeriodStart: e.PeriodStart, PeriodEnd: e.PeriodEnd, Err: err})
+	}
+
+	return nil
+}
+
+func (e *SeriesSegment[Data, Index]) validate(full bool) error {
+	if e.Data == nil {
+		return errors.New("data storage is not initialized")
+	}
+
+	if e.idxCmp(e.PeriodStart, e.PeriodEnd) > 0 {
+		return errors.Errorf("incorrect period bounds: %v > %v", e.PeriodStart, e.PeriodEnd)
+	}
+
+	first, err := e.Data.First(e.PeriodStart)
+	if err != nil {
+		return errors.Wrap(err, "failed to get first data")
+	}
+
+	last, err := e.Data.Last(e.PeriodEnd)
+	if err != nil {
+		return errors.Wrap(err, "failed to get last data")
+	}
 
-// 	if e.idxCmp(e.PeriodStart, e.PeriodEnd) > 0 {
-// 		return errors.Errorf("incorrect period bounds: %v > %v", e.PeriodStart, e.PeriodEnd)
-// 	}
+	if first == nil || last == nil {
+		if first != last {
+			return errors.Errorf("inconsistent storage: first = %v, last = %v", first, last)
+		}
+		if !e.Empty {
+			return errors.Errorf("segment [ %v ; %v ] is marked as non-empty, but has no data", e.PeriodStart, e.PeriodEnd)
+		}
+
+		return nil
+	}
 
-// 	first, err := e.Data.First(e.PeriodStart)
-// 	if err != nil {
-// 		return errors.Wrap(err, "failed to get first data")
-// 	}
-// 	if first == nil {
-// 		return nil
-// 	}
+	if e.Empty {
+		return errors.Errorf("segment [ %v ; %v ] is marked as empty, but has data", e.PeriodStart, e.PeriodEnd)
+	}
 
-//  QWE
+	firstIdx := e.getIdx(first)
+	if e.idxCmp(firstIdx, e.PeriodStart) < 0 {
+		return errors.Errorf("data is out of segment bounds: %v < %v", firstIdx, e.PeriodStart)
+	}
 
-// 	return nil
-// }
+	lastIdx := e.getIdx(last)
+	if e.idxCmp(lastIdx, e.PeriodEnd) > 0 {
+		return errors.Errorf("data is out of segment bounds: %v > %v", lastIdx, e.PeriodEnd)
+	}
+
+	if !full {
+		return nil
+	}
+
+	data, err := e.Data.Get(e.PeriodStart, e.PeriodEnd)
+	if err != nil {
+		return errors.Wrap(err, "failed to get data")
+	}
+
+	for i := 1; i < len(data); i++ {
+		prevIdx := e.getIdx(&data[i-1])
+		idx := e.getIdx(&data[i])
+
+		if e.idxCmp(prevIdx, idx) > 0 {
+			return &UnsortedDataError[Index]{
+				PeriodStart: e.PeriodStart,
+				PeriodEnd:   e.PeriodEnd,
+				Details:     fmt.Sprintf("%v goes before %v", prevIdx, idx),
+			}
+		}
+	}
+
+	if len(data) == 0 || e.idxCmp(e.getIdx(&data[0]), firstIdx) != 0 || e.idxCmp(e.getIdx(&data[len(data)-1]), lastIdx) != 0 {
+		return &UnsortedDataError[Index]{
+			PeriodStart: e.PeriodStart,
+			PeriodEnd:   e.PeriodEnd,
+			Details:     fmt.Sprintf("first = %v, last = %v, data within segment bounds = %v", firstIdx, lastIdx, len(data)),
+		}
+	}
+
+	return nil
+}
@@ -4,6 +4,7 @@ import (
 	"testing"
 
 	"github.com/nnikolash/go-sparse"
+	"github.com/nnikolash/go-sparse/sparsetest"
 	"github.com/stretchr/testify/require"
 )
 
@@ -434,3 +435,23 @@ func TestSeriesSegment_MergeDuplicate(t *testing.T) {
 
 	// TODO: more tests on duplicates
 }
+
+func TestSeriesSegment_FirstLastGroup(t *testing.T) {
+	t.Parallel()
+
+	segment := sparse.NewSeriesSegment[sparsetest.Item, int](sparse.NewArrayData, sparsetest.ItemIdx, sparsetest.ItemIdxCmp, nil)
+
+	first, err := segment.FirstGroup()
+	require.NoError(t, err)
+	require.Empty(t, first)
+
+	require.NoError(t, segment.MergePeriod(0, 10, []sparsetest.Item{item(1, 1), item(1, 2), item(5, 1), item(9, 1), item(9, 2), item(9, 3)}))
+
+	first, err = segment.FirstGroup()
+	require.NoError(t, err)
+	require.Equal(t, []sparsetest.Item{item(1, 1), item(1, 2)}, first)
+
+	last, err := segment.LastGroup()
+	require.NoError(t, err)
+	require.Equal(t, []sparsetest.Item{item(9, 1), item(9, 2), item(9, 3)}, last)
+}
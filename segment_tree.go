@@ -0,0 +1,282 @@
+package sparse
+
+import (
+	"iter"
+	"math/rand/v2"
+)
+
+// Ordered container of segments, which keeps insertion, deletion and lookup by position at O(log n).
+// It is implemented as implicit treap (randomized balanced tree), where position of segment is defined
+// by sizes of subtrees. Each node also counts non-empty segments in its subtree, which allows to find
+// non-empty segments without visiting empty ones.
+type segmentTree[Data any, Index any] struct {
+	root *segmentTreeNode[Data, Index]
+	// Called by Replace with removed segments and segments inserted instead of them.
+	onReplace func(from int, removed, inserted []*SeriesSegment[Data, Index])
+}
+
+type segmentTreeNode[Data any, Index any] struct {
+	segment  *SeriesSegment[Data, Index]
+	priority uint64
+	left     *segmentTreeNode[Data, Index]
+	right    *segmentTreeNode[Data, Index]
+	size     int
+	nonEmpty int
+}
+
+func newSegmentTree[Data any, Index any](segments ...*SeriesSegment[Data, Index]) *segmentTree[Data, Index] {
+	t := &segmentTree[Data, Index]{}
+	t.Replace(0, 0, segments...)
+	return t
+}
+
+func (t *segmentTree[Data, Index]) Len() int {
+	return t.root.getSize()
+}
+
+func (t *segmentTree[Data, Index]) NonEmptyLen() int {
+	return t.root.getNonEmpty()
+}
+
+func (t *segmentTree[Data, Index]) At(pos int) *SeriesSegment[Data, Index] {
+	n := t.root
+
+	for n != nil {
+		leftSize := n.left.getSize()
+
+		switch {
+		case pos < leftSize:
+			n = n.left
+		case pos == leftSize:
+			return n.segment
+		default:
+			pos -= leftSize + 1
+			n = n.right
+		}
+	}
+
+	panic("segment position is out of range")
+}
+
+// Returns non-empty segment by its rank among non-empty segments.
+func (t *segmentTree[Data, Index]) NonEmptyAt(rank int) *SeriesSegment[Data, Index] {
+	n := t.root
+
+	for n != nil {
+		leftNonEmpty := n.left.getNonEmpty()
+
+		if rank < leftNonEmpty {
+			n = n.left
+			continue
+		}
+
+		rank -= leftNonEmpty
+
+		if !n.segment.Empty {
+			if rank == 0 {
+				return n.segment
+			}
+			rank--
+		}
+
+		n = n.right
+	}
+
+	panic("non-empty segment rank is out of range")
+}
+
+// Same as sort.Search: returns the smallest position for which f is true, or Len() if there is no such position.
+// f must be false for some prefix of segments and true for the rest of them.
+func (t *segmentTree[Data, Index]) Search(f func(segment *SeriesSegment[Data, Index]) bool) int {
+	pos := 0
+	n := t.root
+
+	for n != nil {
+		if f(n.segment) {
+			n = n.left
+		} else {
+			pos += n.left.getSize() + 1
+			n = n.right
+		}
+	}
+
+	return pos
+}
+
+// Same as Search, but returns rank among non-empty segments.
+func (t *segmentTree[Data, Index]) SearchNonEmpty(f func(segment *SeriesSegment[Data, Index]) bool) int {
+	rank := 0
+	n := t.root
+
+	for n != nil {
+		if f(n.segment) {
+			n = n.left
+		} else {
+			rank += n.left.getNonEmpty()
+			if !n.segment.Empty {
+				rank++
+			}
+			n = n.right
+		}
+	}
+
+	return rank
+}
+
+// Replaces segments at positions [from; to) with provided segments.
+// Must be called for every modified segment to keep count of non-empty segments correct.
+func (t *segmentTree[Data, Index]) Replace(from, to int, segments ...*SeriesSegment[Data, Index]) {
+	left, rest := splitSegmentTree(t.root, from)
+	removed, right := splitSegmentTree(rest, to-from)
+
+	if t.onReplace != nil {
+		var removedSegments []*SeriesSegment[Data, Index]
+		removed.forEach(func(segment *SeriesSegment[Data, Index]) {
+			removedSegments = append(removedSegments, segment)
+		})
+
+		t.onReplace(from, removedSegments, segments)
+	}
+
+	for _, segment := range segments {
+		n := &segmentTreeNode[Data, Index]{
+			segment:  segment,
+			priority: rand.Uint64(),
+		}
+		n.update()
+
+		left = mergeSegmentTrees(left, n)
+	}
+
+	t.root = mergeSegmentTrees(left, right)
+}
+
+// Returns sequence of segments starting from position.
+func (t *segmentTree[Data, Index]) Values(from int) iter.Seq[*SeriesSegment[Data, Index]] {
+	return func(yield func(*SeriesSegment[Data, Index]) bool) {
+		var stack []*segmentTreeNode[Data, Index]
+
+		n := t.root
+		for n != nil {
+			leftSize := n.left.getSize()
+
+			if from <= leftSize {
+				stack = append(stack, n)
+				if from == leftSize {
+					break
+				}
+				n = n.left
+			} else {
+				from -= leftSize + 1
+				n = n.right
+			}
+		}
+
+		for len(stack) > 0 {
+			n = stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+
+			if !yield(n.segment) {
+				return
+			}
+
+			for c := n.right; c != nil; c = c.left {
+				stack = append(stack, c)
+			}
+		}
+	}
+}
+
+// Returns sequence of non-empty segments starting from rank.
+func (t *segmentTree[Data, Index]) NonEmptyValues(from int) iter.Seq[*SeriesSegment[Data, Index]] {
+	return func(yield func(*SeriesSegment[Data, Index]) bool) {
+		for rank := from; rank < t.NonEmptyLen(); rank++ {
+			if !yield(t.NonEmptyAt(rank)) {
+				return
+			}
+		}
+	}
+}
+
+func (t *segmentTree[Data, Index]) Slice() []*SeriesSegment[Data, Index] {
+	segments := make([]*SeriesSegment[Data, Index], 0, t.Len())
+	for segment := range t.Values(0) {
+		segments = append(segments, segment)
+	}
+
+	return segments
+}
+
+func (n *segmentTreeNode[Data, Index]) getSize() int {
+	if n == nil {
+		return 0
+	}
+
+	return n.size
+}
+
+func (n *segmentTreeNode[Data, Index]) getNonEmpty() int {
+	if n == nil {
+		return 0
+	}
+
+	return n.nonEmpty
+}
+
+// Calls f for each segment of subtree in order.
+func (n *segmentTreeNode[Data, Index]) forEach(f func(segment *SeriesSegment[Data, Index])) {
+	if n == nil {
+		return
+	}
+
+	n.left.forEach(f)
+	f(n.segment)
+	n.right.forEach(f)
+}
+
+func (n *segmentTreeNode[Data, Index]) update() {
+	n.size = n.left.getSize() + n.right.getSize() + 1
+	n.nonEmpty = n.left.getNonEmpty() + n.right.getNonEmpty()
+
+	if !n.segment.Empty {
+		n.nonEmpty++
+	}
+}
+
+// Splits tree into first count nodes and the rest of them.
+func splitSegmentTree[Data any, Index any](n *segmentTreeNode[Data, Index], count int) (left, right *segmentTreeNode[Data, Index]) {
+	if n == nil {
+		return nil, nil
+	}
+
+	if n.left.getSize() >= count {
+		left, n.left = splitSegmentTree(n.left, count)
+		n.update()
+		return left, n
+	}
+
+	n.right, right = splitSegmentTree(n.right, count-n.left.getSize()-1)
+	n.update()
+
+	return n, right
+}
+
+func mergeSegmentTrees[Data any, Index any](left, right *segmentTreeNode[Data, Index]) *segmentTreeNode[Data, Index] {
+	if left == nil {
+		return right
+	}
+	if right == nil {
+		return left
+	}
+
+	if left.priority > right.priority {
+		left.right = mergeSegmentTrees(left.right, right)
+		left.update()
+		return left
+	}
+
+	right.left = mergeSegmentTrees(left, right.left)
+	right.update()
+
+	return right
+}
@@ -3,7 +3,6 @@ package sparse
 import (
 	"fmt"
 	"slices"
-	"sort"
 	"strings"
 
 	"github.com/pkg/errors"
@@ -22,12 +21,16 @@ func NewSeries[Data any, Index any](
 		areContinuous = func(smaller, bigger Index) bool { return false }
 	}
 
-	return &Series[Data, Index]{
+	s := &Series[Data, Index]{
 		dataFactory:   storageFactory,
 		getIdx:        getIdx,
 		idxCmp:        cmp,
 		areContinuous: areContinuous,
+		segments:      newSegmentTree[Data, Index](),
 	}
+	s.segments.onReplace = s.segmentsReplaced
+
+	return s
 }
 
 type Series[Data any, Index any] struct {
@@ -35,24 +38,36 @@ type Series[Data any, Index any] struct {
 	getIdx        func(data *Data) Index
 	idxCmp        func(idx1, idx2 Index) int
 	areContinuous func(smaller, bigger Index) bool
-	segments      []*SeriesSegment[Data, Index]
+	segments      *segmentTree[Data, Index]
+	retention     *RetentionPolicy[Index]
+	codec         Codec[Data, Index]
+	wal           *WAL[Data, Index]
+	mergePolicy   *MergePolicy[Data]
+	tiering       *seriesTiering[Data, Index]
+	// Storages of removed segments, which are not released yet.
+	removed []SeriesData[Data, Index]
+	// Changes of modification in progress.
+	journal *seriesJournal[Data, Index]
+
+	validateOnChange bool
 }
 
 func (s *Series[Data, Index]) Segments() []*SeriesSegment[Data, Index] {
-	return s.segments
+	return s.segments.Slice()
 }
 
 // For debugging purposes
 func (s *Series[Data, Index]) SegmentsString() string {
 	var res strings.Builder
-	for _, segment := range s.segments {
+	for segment := range s.segments.Values(0) {
 		if res.Len() > 0 {
 			res.WriteString("| ")
 		}
 
 		data, err := segment.Data.Get(segment.PeriodStart, segment.PeriodEnd)
 		if err != nil {
-			panic(fmt.Errorf("could not get data: %v", err))
+			res.WriteString(fmt.Sprintf("<could not get data: %v> ", err))
+			continue
 		}
 
 		for _, elem := range data {
@@ -64,24 +79,65 @@ func (s *Series[Data, Index]) SegmentsString() string {
 }
 
 func (s *Series[Data, Index]) GetAllSegments() []*SeriesSegment[Data, Index] {
-	if len(s.segments) == 0 {
+	if s.segments.Len() == 0 {
 		return nil
 	}
 
-	return s.segments
+	return s.segments.Slice()
 }
 
 func (s *Series[Data, Index]) GetSegment(t Index) *SeriesSegment[Data, Index] {
+	if s.segments.Len() == 0 {
+		return nil
+	}
+
 	segmentIdx, contains := s.findSegmentWhichStartsBeforeOrAt(t, false)
 	if segmentIdx == -1 && !contains {
 		return nil
 	}
 
-	return s.segments[segmentIdx]
+	return s.segments.At(segmentIdx)
 }
 
 func (s *Series[Data, Index]) Get(periodStart, periodEnd Index) ([]Data, error) {
-	if len(s.segments) == 0 {
+	segment, err := s.getCoveringSegment(periodStart, periodEnd)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := segment.Data.Get(periodStart, periodEnd)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) == 0 {
+		return nil, nil
+	}
+
+	firstIdx := s.getIdx(&data[0])
+	if s.idxCmp(firstIdx, periodStart) < 0 {
+		return nil, errors.WithStack(&UnsortedDataError[Index]{
+			PeriodStart: periodStart,
+			PeriodEnd:   periodEnd,
+			Details:     fmt.Sprintf("first index is before period start: %v < %v", firstIdx, periodStart),
+		})
+	}
+
+	lastIdx := s.getIdx(&data[len(data)-1])
+	if s.idxCmp(lastIdx, periodEnd) > 0 {
+		return nil, errors.WithStack(&UnsortedDataError[Index]{
+			PeriodStart: periodStart,
+			PeriodEnd:   periodEnd,
+			Details:     fmt.Sprintf("last index is after period end: %v > %v", lastIdx, periodEnd),
+		})
+	}
+
+	return data, nil
+}
+
+// Returns the segment, which fully covers requested period.
+func (s *Series[Data, Index]) getCoveringSegment(periodStart, periodEnd Index) (*SeriesSegment[Data, Index], error) {
+	if s.segments.Len() == 0 {
 		return nil, errors.WithStack(&MissingPeriodError[Index]{PeriodStart: periodStart, PeriodEnd: periodEnd})
 	}
 	if s.idxCmp(periodStart, periodEnd) > 0 {
@@ -95,12 +151,12 @@ func (s *Series[Data, Index]) Get(periodStart, periodEnd Index) ([]Data, error)
 
 	intersectFirstSegmentIdx, firstContains := s.findSegmentWhichStartsBeforeOrAt(periodStart, false)
 	if intersectFirstSegmentIdx == -1 {
-		currentBeginning := s.segments[0].PeriodStart
+		currentBeginning := s.segments.At(0).PeriodStart
 		return nil, errors.WithStack(&MissingPeriodError[Index]{PeriodStart: periodStart, PeriodEnd: currentBeginning})
 	}
 
-	intersectFirstSegment := s.segments[intersectFirstSegmentIdx]
-	intersectLastSegment := s.segments[intersectLastSegmentIdx]
+	intersectFirstSegment := s.segments.At(intersectFirstSegmentIdx)
+	intersectLastSegment := s.segments.At(intersectLastSegmentIdx)
 
 	if intersectFirstSegmentIdx != intersectLastSegmentIdx || !firstContains || !lastContains {
 		if firstContains {
@@ -112,32 +168,53 @@ func (s *Series[Data, Index]) Get(periodStart, periodEnd Index) ([]Data, error)
 		return nil, errors.WithStack(&MissingPeriodError[Index]{PeriodStart: periodStart, PeriodEnd: periodEnd})
 	}
 
-	segment := intersectFirstSegment
-
-	data, err := segment.Data.Get(periodStart, periodEnd)
-	if err != nil {
+	if err := s.touchSegment(intersectFirstSegment); err != nil {
 		return nil, err
 	}
 
-	if len(data) == 0 {
-		return nil, nil
+	return intersectFirstSegment, nil
+}
+
+// Returns data of each covered sub-period of [ periodStart ; periodEnd ] together with missing sub-periods.
+func (s *Series[Data, Index]) GetAvailable(periodStart, periodEnd Index) (available []PeriodData[Data, Index], missing []PeriodBounds[Index], _ error) {
+	if s.idxCmp(periodStart, periodEnd) > 0 {
+		return nil, nil, errors.Errorf("requested period start is greater than period end: %v > %v", periodStart, periodEnd)
 	}
 
-	firstIdx := s.getIdx(&data[0])
-	if s.idxCmp(firstIdx, periodStart) < 0 {
-		return nil, errors.Errorf("storage error: data is not sorted: firstIdx > periodStart: %v > %v", firstIdx, periodStart)
+	missing = s.MissingPeriods(periodStart, periodEnd)
+
+	if s.segments.Len() == 0 {
+		return nil, missing, nil
 	}
 
-	lastIdx := s.getIdx(&data[len(data)-1])
-	if s.idxCmp(lastIdx, periodEnd) > 0 {
-		return nil, errors.Errorf("storage error: data is not sorted: lastIdx < periodEnd: %v < %v", lastIdx, periodEnd)
+	intersectLastSegmentIdx, _ := s.findSegmentWhichStartsBeforeOrAt(periodEnd, false)
+	intersectFirstSegmentIdx, firstContains := s.findSegmentWhichStartsBeforeOrAt(periodStart, false)
+	if !firstContains {
+		intersectFirstSegmentIdx++
 	}
 
-	return data, nil
+	for i := intersectFirstSegmentIdx; i <= intersectLastSegmentIdx; i++ {
+		segment := s.segments.At(i)
+		if err := s.touchSegment(segment); err != nil {
+			return nil, nil, err
+		}
+
+		fetchedPeriodStart, fetchedPeriodEnd, data, err := segment.GetAllInRange(periodStart, periodEnd)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		available = append(available, PeriodData[Data, Index]{
+			PeriodBounds: PeriodBounds[Index]{PeriodStart: fetchedPeriodStart, PeriodEnd: fetchedPeriodEnd},
+			Data:         data,
+		})
+	}
+
+	return available, missing, nil
 }
 
 func (s *Series[Data, Index]) GetPeriod(periodStart, periodEnd Index) *SeriesSegment[Data, Index] {
-	if len(s.segments) == 0 {
+	if s.segments.Len() == 0 {
 		return nil
 	}
 
@@ -147,7 +224,7 @@ func (s *Series[Data, Index]) GetPeriod(periodStart, periodEnd Index) *SeriesSeg
 	}
 
 	if s.idxCmp(periodStart, periodEnd) == 0 {
-		return s.segments[firstSegmentIdx]
+		return s.segments.At(firstSegmentIdx)
 	}
 
 	lastSegmentIdx, contains := s.findSegmentWhichStartsBeforeOrAt(periodEnd, false)
@@ -159,11 +236,20 @@ func (s *Series[Data, Index]) GetPeriod(periodStart, periodEnd Index) *SeriesSeg
 		return nil
 	}
 
-	return s.segments[firstSegmentIdx]
+	return s.segments.At(firstSegmentIdx)
 }
 
 func (s *Series[Data, Index]) GetPeriodClosestFromStart(t Index, nonEmpty bool) *SeriesSegment[Data, Index] {
-	if len(s.segments) == 0 {
+	if nonEmpty {
+		segmentIdx := s.findNonEmptySegmentWhichStartsBeforeOrAt(t)
+		if segmentIdx == -1 {
+			return nil
+		}
+
+		return s.segments.NonEmptyAt(segmentIdx)
+	}
+
+	if s.segments.Len() == 0 {
 		return nil
 	}
 
@@ -172,21 +258,20 @@ func (s *Series[Data, Index]) GetPeriodClosestFromStart(t Index, nonEmpty bool)
 		return nil
 	}
 
+	return s.segments.At(segmentIdx)
+}
+
+func (s *Series[Data, Index]) GetPeriodClosestFromEnd(t Index, nonEmpty bool) *SeriesSegment[Data, Index] {
 	if nonEmpty {
-		// TODO: this is ineffective... but I just hope there will be not much of empty segments
-		for segmentIdx >= 0 && s.segments[segmentIdx].Empty {
-			segmentIdx--
-		}
-		if segmentIdx == -1 {
+		segmentIdx := s.findNonEmptySegmentWhichEndsAfterOrAt(t)
+		if segmentIdx == s.segments.NonEmptyLen() {
 			return nil
 		}
-	}
 
-	return s.segments[segmentIdx]
-}
+		return s.segments.NonEmptyAt(segmentIdx)
+	}
 
-func (s *Series[Data, Index]) GetPeriodClosestFromEnd(t Index, nonEmpty bool) *SeriesSegment[Data, Index] {
-	if len(s.segments) == 0 {
+	if s.segments.Len() == 0 {
 		return nil
 	}
 
@@ -194,23 +279,54 @@ func (s *Series[Data, Index]) GetPeriodClosestFromEnd(t Index, nonEmpty bool) *S
 	if segmentIdx == -1 {
 		segmentIdx = 0
 	} else if !contains {
-		if segmentIdx == len(s.segments)-1 {
+		if segmentIdx == s.segments.Len()-1 {
 			return nil
 		}
 		segmentIdx++
 	}
 
-	if nonEmpty {
-		// TODO: this is ineffective... but I just hope there will be not much of empty segments
-		for segmentIdx < len(s.segments) && s.segments[segmentIdx].Empty {
-			segmentIdx++
-		}
-		if segmentIdx == len(s.segments) {
-			return nil
+	return s.segments.At(segmentIdx)
+}
+
+// Returns all sub-periods of [ periodStart ; periodEnd ], which are not covered by segments.
+// Bounds of each missing period touch adjacent segments, so adding it would join them.
+func (s *Series[Data, Index]) MissingPeriods(periodStart, periodEnd Index) []PeriodBounds[Index] {
+	if s.idxCmp(periodStart, periodEnd) > 0 {
+		return nil
+	}
+	if s.segments.Len() == 0 {
+		return []PeriodBounds[Index]{{PeriodStart: periodStart, PeriodEnd: periodEnd}}
+	}
+
+	intersectFirstSegmentIdx, firstContains := s.findSegmentWhichStartsBeforeOrAt(periodStart, false)
+	intersectLastSegmentIdx, _ := s.findSegmentWhichStartsBeforeOrAt(periodEnd, false)
+
+	var missing []PeriodBounds[Index]
+
+	gapStart := periodStart
+	gapStartCovered := false
+
+	if firstContains {
+		gapStart = s.segments.At(intersectFirstSegmentIdx).PeriodEnd
+		gapStartCovered = true
+	}
+
+	for i := intersectFirstSegmentIdx + 1; i <= intersectLastSegmentIdx; i++ {
+		segment := s.segments.At(i)
+
+		if !gapStartCovered || !s.areContinuous(gapStart, segment.PeriodStart) {
+			missing = append(missing, PeriodBounds[Index]{PeriodStart: gapStart, PeriodEnd: segment.PeriodStart})
 		}
+
+		gapStart = segment.PeriodEnd
+		gapStartCovered = true
 	}
 
-	return s.segments[segmentIdx]
+	if !gapStartCovered || s.idxCmp(gapStart, periodEnd) < 0 {
+		missing = append(missing, PeriodBounds[Index]{PeriodStart: gapStart, PeriodEnd: periodEnd})
+	}
+
+	return missing
 }
 
 func (s *Series[Data, Index]) AddData(data []Data) error {
@@ -225,14 +341,61 @@ func (s *Series[Data, Index]) AddData(data []Data) error {
 }
 
 func (s *Series[Data, Index]) AddPeriod(periodStart, periodEnd Index, data []Data) error {
-	if len(s.segments) == 0 {
+	combined, err := s.combineWithExisting(periodStart, periodEnd, data)
+	if err != nil {
+		return err
+	}
+
+	// Original data is logged, because it is combined with existing data again on replay
+	if err := s.logAddPeriod(periodStart, periodEnd, data); err != nil {
+		return err
+	}
+
+	err = s.modify(func() error {
+		return s.applyAddPeriod(periodStart, periodEnd, combined)
+	})
+	if err != nil {
+		return s.rollbackLogged(err)
+	}
+
+	return nil
+}
+
+func (s *Series[Data, Index]) applyAddPeriod(periodStart, periodEnd Index, data []Data) error {
+	if err := s.addPeriod(periodStart, periodEnd, data); err != nil {
+		return err
+	}
+
+	if err := s.applyRetention(); err != nil {
+		return err
+	}
+
+	if err := s.applyTiering(s.GetPeriod(periodStart, periodEnd)); err != nil {
+		return err
+	}
+
+	return s.validateAfterChange()
+}
+
+// Data of added period is already combined with existing data, if series has merge policy,
+// so storage must not combine it again using its own policy.
+func (s *Series[Data, Index]) mergeAddedPeriod(segment *SeriesSegment[Data, Index], periodStart, periodEnd Index, data []Data) error {
+	if err := s.backupSegmentData(segment, periodStart, periodEnd); err != nil {
+		return err
+	}
+
+	return segment.mergePeriod(periodStart, periodEnd, data, !s.mergePolicy.isOverwrite())
+}
+
+func (s *Series[Data, Index]) addPeriod(periodStart, periodEnd Index, data []Data) error {
+	if s.segments.Len() == 0 {
 		newSegment := NewSeriesSegment[Data, Index](s.dataFactory, s.getIdx, s.idxCmp, s.areContinuous)
 
-		if err := newSegment.MergePeriod(periodStart, periodEnd, data); err != nil {
+		if err := s.mergeAddedPeriod(newSegment, periodStart, periodEnd, data); err != nil {
 			return err
 		}
 
-		s.segments = append(s.segments, newSegment)
+		s.segments.Replace(0, 0, newSegment)
 
 		return nil
 	}
@@ -240,6 +403,11 @@ func (s *Series[Data, Index]) AddPeriod(periodStart, periodEnd Index, data []Dat
 	intersectFirstSegmentIdx, firstContains := s.findSegmentWhichStartsBeforeOrAt(periodStart, true)
 	intersectLastSegmentIdx, lastContains := s.findSegmentWhichStartsBeforeOrAt(periodEnd, true)
 
+	// Period start may be continuous with both the end of previous segment and the start of the found one.
+	if intersectFirstSegmentIdx > 0 && s.segments.At(intersectFirstSegmentIdx-1).CanBeMergedWith(periodStart) {
+		intersectFirstSegmentIdx--
+	}
+
 	endsBeforeStart := intersectLastSegmentIdx == -1
 	if endsBeforeStart {
 		return s.insertBeforeStart(periodStart, periodEnd, data)
@@ -250,7 +418,7 @@ func (s *Series[Data, Index]) AddPeriod(periodStart, periodEnd Index, data []Dat
 		return s.mergeWithStart(periodStart, periodEnd, data, intersectLastSegmentIdx)
 	}
 
-	lastSegmentIdx := len(s.segments) - 1
+	lastSegmentIdx := s.segments.Len() - 1
 
 	if intersectFirstSegmentIdx == lastSegmentIdx && !firstContains {
 		return s.insertAfterEnd(periodStart, periodEnd, data)
@@ -263,10 +431,135 @@ func (s *Series[Data, Index]) AddPeriod(periodStart, periodEnd Index, data []Dat
 	return s.mergeWithinRange(periodStart, periodEnd, data, intersectFirstSegmentIdx, intersectLastSegmentIdx)
 }
 
+// Removes data and coverage of the closed period [ periodStart ; periodEnd ].
+// Segment, which extends beyond the period, keeps coverage only up to its last item before the period
+// and from its first item after it, because neighbouring indexes of period bounds are unknown.
+func (s *Series[Data, Index]) DeletePeriod(periodStart, periodEnd Index) error {
+	if err := s.logDelete(periodStart, periodEnd); err != nil {
+		return err
+	}
+
+	err := s.modify(func() error {
+		return s.applyDeletePeriod(periodStart, periodEnd)
+	})
+	if err != nil {
+		return s.rollbackLogged(err)
+	}
+
+	return nil
+}
+
+func (s *Series[Data, Index]) applyDeletePeriod(periodStart, periodEnd Index) error {
+	if err := s.deletePeriod(periodStart, periodEnd); err != nil {
+		return err
+	}
+
+	if err := s.applyTiering(s.GetPeriodClosestFromStart(periodStart, false), s.GetPeriodClosestFromEnd(periodEnd, false)); err != nil {
+		return err
+	}
+
+	return s.validateAfterChange()
+}
+
+func (s *Series[Data, Index]) deletePeriod(periodStart, periodEnd Index) error {
+	if s.idxCmp(periodStart, periodEnd) > 0 {
+		return errors.Errorf("requested period start is greater than period end: %v > %v", periodStart, periodEnd)
+	}
+	if s.segments.Len() == 0 {
+		return nil
+	}
+
+	intersectLastSegmentIdx, _ := s.findSegmentWhichStartsBeforeOrAt(periodEnd, false)
+	if intersectLastSegmentIdx == -1 {
+		return nil
+	}
+
+	intersectFirstSegmentIdx, firstContains := s.findSegmentWhichStartsBeforeOrAt(periodStart, false)
+	if !firstContains {
+		intersectFirstSegmentIdx++
+	}
+	if intersectFirstSegmentIdx > intersectLastSegmentIdx {
+		return nil
+	}
+
+	remainingSegments := make([]*SeriesSegment[Data, Index], 0, 2)
+
+	for i := intersectFirstSegmentIdx; i <= intersectLastSegmentIdx; i++ {
+		segment := s.segments.At(i)
+
+		keepStart := s.idxCmp(segment.PeriodStart, periodStart) < 0
+		keepEnd := s.idxCmp(segment.PeriodEnd, periodEnd) > 0
+
+		switch {
+		case keepStart && keepEnd:
+			if err := s.backupSegmentData(segment, periodStart, segment.PeriodEnd); err != nil {
+				return err
+			}
+
+			endData, err := segment.Data.Get(periodEnd, segment.PeriodEnd)
+			if err != nil {
+				return err
+			}
+
+			endSegment := NewSeriesSegment(s.dataFactory, s.getIdx, s.idxCmp, s.areContinuous)
+			if err := endSegment.MergePeriod(periodEnd, segment.PeriodEnd, slices.Clone(endData)); err != nil {
+				return err
+			}
+			if err := endSegment.deleteStart(periodEnd); err != nil {
+				return err
+			}
+
+			if err := segment.deleteEnd(periodStart); err != nil {
+				return err
+			}
+
+			remainingSegments = append(remainingSegments, segment, endSegment)
+		case keepStart:
+			if err := s.backupSegmentData(segment, periodStart, segment.PeriodEnd); err != nil {
+				return err
+			}
+			if err := segment.deleteEnd(periodStart); err != nil {
+				return err
+			}
+
+			remainingSegments = append(remainingSegments, segment)
+		case keepEnd:
+			if err := s.backupSegmentData(segment, segment.PeriodStart, periodEnd); err != nil {
+				return err
+			}
+			if err := segment.deleteStart(periodEnd); err != nil {
+				return err
+			}
+
+			remainingSegments = append(remainingSegments, segment)
+		}
+	}
+
+	s.segments.Replace(intersectFirstSegmentIdx, intersectLastSegmentIdx+1, remainingSegments...)
+
+	return nil
+}
+
+// Replaces content of the series with provided segments. Only bounds of segments are checked against the first and the last items
+// of their data, unless validation on change is enabled.
 func (s *Series[Data, Index]) Restore(state *SeriesState[Data, Index]) error {
+	segments, err := s.restoredSegments(state)
+	if err != nil {
+		return err
+	}
+
+	return s.replaceSegments(segments)
+}
+
+// Creates and validates segments of the state without changing the series.
+func (s *Series[Data, Index]) restoredSegments(state *SeriesState[Data, Index]) ([]*SeriesSegment[Data, Index], error) {
 	for _, segment := range state.Segments {
 		if s.idxCmp(segment.PeriodStart, segment.PeriodEnd) > 0 {
-			return errors.Errorf("storage error: segment period start is greater than period end: %v > %v", segment.PeriodStart, segment.PeriodEnd)
+			return nil, errors.WithStack(&StorageCorruptedError[Index]{
+				PeriodStart: segment.PeriodStart,
+				PeriodEnd:   segment.PeriodEnd,
+				Err:         errors.Errorf("segment period start is greater than period end: %v > %v", segment.PeriodStart, segment.PeriodEnd),
+			})
 		}
 	}
 
@@ -279,19 +572,30 @@ func (s *Series[Data, Index]) Restore(state *SeriesState[Data, Index]) error {
 		segments = append(segments, e)
 	}
 
-	s.segments = segments
+	// Restored storages may be backed by files, so all their data is read only if validation on change is enabled
+	if err := s.validateSegments(slices.Values(segments), s.validateOnChange); err != nil {
+		return nil, errors.Wrap(err, "storage error")
+	}
 
-	return nil
+	return segments, nil
+}
+
+func (s *Series[Data, Index]) replaceSegments(segments []*SeriesSegment[Data, Index]) error {
+	previous := s.segments
+	s.segments = newSegmentTree(segments...)
+	s.segments.onReplace = s.segmentsReplaced
+
+	return s.resetTiering(previous)
 }
 
 func (s *Series[Data, Index]) insertBeforeStart(periodStart, periodEnd Index, data []Data) error {
 	newSegment := NewSeriesSegment[Data, Index](s.dataFactory, s.getIdx, s.idxCmp, s.areContinuous)
 
-	if err := newSegment.MergePeriod(periodStart, periodEnd, data); err != nil {
+	if err := s.mergeAddedPeriod(newSegment, periodStart, periodEnd, data); err != nil {
 		return err
 	}
 
-	s.segments = slices.Insert(s.segments, 0, newSegment)
+	s.segments.Replace(0, 0, newSegment)
 
 	return nil
 }
@@ -299,9 +603,9 @@ func (s *Series[Data, Index]) insertBeforeStart(periodStart, periodEnd Index, da
 func (s *Series[Data, Index]) mergeWithStart(periodStart, periodEnd Index, data []Data, intersectLastSegmentIdx int) error {
 	var newFirstSegment *SeriesSegment[Data, Index]
 
-	intersectLastSegment := s.segments[intersectLastSegmentIdx]
+	intersectLastSegment := s.segments.At(intersectLastSegmentIdx)
 	if intersectLastSegment.CanBeMergedWith(periodEnd) {
-		if err := intersectLastSegment.MergePeriod(periodStart, periodEnd, data); err != nil {
+		if err := s.mergeAddedPeriod(intersectLastSegment, periodStart, periodEnd, data); err != nil {
 			return err
 		}
 
@@ -309,13 +613,12 @@ func (s *Series[Data, Index]) mergeWithStart(periodStart, periodEnd Index, data
 	} else {
 		newFirstSegment = NewSeriesSegment(s.dataFactory, s.getIdx, s.idxCmp, s.areContinuous)
 
-		if err := newFirstSegment.MergePeriod(periodStart, periodEnd, data); err != nil {
+		if err := s.mergeAddedPeriod(newFirstSegment, periodStart, periodEnd, data); err != nil {
 			return err
 		}
 	}
 
-	s.segments = slices.Delete(s.segments, 0, intersectLastSegmentIdx)
-	s.segments[0] = newFirstSegment
+	s.segments.Replace(0, intersectLastSegmentIdx+1, newFirstSegment)
 
 	return nil
 }
@@ -323,49 +626,53 @@ func (s *Series[Data, Index]) mergeWithStart(periodStart, periodEnd Index, data
 func (s *Series[Data, Index]) insertAfterEnd(periodStart, periodEnd Index, data []Data) error {
 	newSegment := NewSeriesSegment[Data, Index](s.dataFactory, s.getIdx, s.idxCmp, s.areContinuous)
 
-	if err := newSegment.MergePeriod(periodStart, periodEnd, data); err != nil {
+	if err := s.mergeAddedPeriod(newSegment, periodStart, periodEnd, data); err != nil {
 		return err
 	}
 
-	s.segments = append(s.segments, newSegment)
+	s.segments.Replace(s.segments.Len(), s.segments.Len(), newSegment)
 
 	return nil
 }
 
 func (s *Series[Data, Index]) mergeWithEnd(periodStart, periodEnd Index, data []Data, intersectFirstSegmentIdx int) error {
-	lastSegmentsToDelete := len(s.segments) - intersectFirstSegmentIdx - 1
-
-	intersectFirstSegment := s.segments[intersectFirstSegmentIdx]
+	intersectFirstSegment := s.segments.At(intersectFirstSegmentIdx)
 	if intersectFirstSegment.CanBeMergedWith(periodStart) {
-		if err := intersectFirstSegment.MergePeriod(periodStart, periodEnd, data); err != nil {
+		if err := s.mergeAddedPeriod(intersectFirstSegment, periodStart, periodEnd, data); err != nil {
 			return err
 		}
-	} else {
-		lastSegmentsToDelete--
 
-		newSegment := NewSeriesSegment(s.dataFactory, s.getIdx, s.idxCmp, s.areContinuous)
+		s.segments.Replace(intersectFirstSegmentIdx, s.segments.Len(), intersectFirstSegment)
 
-		if err := newSegment.MergePeriod(periodStart, periodEnd, data); err != nil {
-			return err
-		}
+		return nil
+	}
 
-		s.segments[intersectFirstSegmentIdx+1] = newSegment
+	newSegment := NewSeriesSegment(s.dataFactory, s.getIdx, s.idxCmp, s.areContinuous)
+
+	if err := s.mergeAddedPeriod(newSegment, periodStart, periodEnd, data); err != nil {
+		return err
 	}
 
-	s.segments = slices.Delete(s.segments, len(s.segments)-lastSegmentsToDelete, len(s.segments))
+	s.segments.Replace(intersectFirstSegmentIdx+1, s.segments.Len(), newSegment)
 
 	return nil
 }
 
 func (s *Series[Data, Index]) mergeWithinRange(periodStart, periodEnd Index, data []Data, intersectFirstSegmentIdx, intersectLastSegmentIdx int) error {
-	firstSegment := s.segments[intersectFirstSegmentIdx]
+	firstSegment := s.segments.At(intersectFirstSegmentIdx)
 	canBeMergedWithFirst := firstSegment.CanBeMergedWith(periodStart)
 
 	if intersectFirstSegmentIdx == intersectLastSegmentIdx && canBeMergedWithFirst {
-		return firstSegment.MergePeriod(periodStart, periodEnd, data)
+		if err := s.mergeAddedPeriod(firstSegment, periodStart, periodEnd, data); err != nil {
+			return err
+		}
+
+		s.segments.Replace(intersectFirstSegmentIdx, intersectFirstSegmentIdx+1, firstSegment)
+
+		return nil
 	}
 
-	lastSegment := s.segments[intersectLastSegmentIdx]
+	lastSegment := s.segments.At(intersectLastSegmentIdx)
 	canBeMergedWithLast := lastSegment.CanBeMergedWith(periodEnd)
 
 	if canBeMergedWithFirst && canBeMergedWithLast {
@@ -377,15 +684,11 @@ func (s *Series[Data, Index]) mergeWithinRange(periodStart, periodEnd Index, dat
 		data = append(firstSegmentData, data...)
 		periodStart = s.getSmallerIndex(firstSegment.PeriodStart, periodStart)
 
-		if err := lastSegment.MergePeriod(periodStart, periodEnd, data); err != nil {
+		if err := s.mergeAddedPeriod(lastSegment, periodStart, periodEnd, data); err != nil {
 			return err
 		}
 
-		s.segments[intersectFirstSegmentIdx] = lastSegment
-
-		deleteFrom := intersectFirstSegmentIdx + 1
-		segmentsToDelete := intersectLastSegmentIdx - intersectFirstSegmentIdx
-		s.segments = slices.Delete(s.segments, deleteFrom, deleteFrom+segmentsToDelete)
+		s.segments.Replace(intersectFirstSegmentIdx, intersectLastSegmentIdx+1, lastSegment)
 
 		return nil
 	}
@@ -394,50 +697,44 @@ func (s *Series[Data, Index]) mergeWithinRange(periodStart, periodEnd Index, dat
 	//segmentsToDelete := intersectLastSegmentIdx - intersectFirstSegmentIdx
 
 	if canBeMergedWithFirst {
-		if err := firstSegment.MergePeriod(periodStart, periodEnd, data); err != nil {
+		if err := s.mergeAddedPeriod(firstSegment, periodStart, periodEnd, data); err != nil {
 			return err
 		}
 
-		deleteFrom := intersectFirstSegmentIdx + 1
-		segmentsToDelete := intersectLastSegmentIdx - intersectFirstSegmentIdx
-		s.segments = slices.Delete(s.segments, deleteFrom, deleteFrom+segmentsToDelete)
+		s.segments.Replace(intersectFirstSegmentIdx, intersectLastSegmentIdx+1, firstSegment)
 
 		return nil
 	}
 
 	if canBeMergedWithLast {
-		if err := lastSegment.MergePeriod(periodStart, periodEnd, data); err != nil {
+		if err := s.mergeAddedPeriod(lastSegment, periodStart, periodEnd, data); err != nil {
 			return err
 		}
 
-		deleteFrom := intersectFirstSegmentIdx + 1
-		segmentsToDelete := intersectLastSegmentIdx - intersectFirstSegmentIdx - 1
-		s.segments = slices.Delete(s.segments, deleteFrom, deleteFrom+segmentsToDelete)
+		s.segments.Replace(intersectFirstSegmentIdx+1, intersectLastSegmentIdx+1, lastSegment)
 
 		return nil
 	}
 
 	newSegment := NewSeriesSegment(s.dataFactory, s.getIdx, s.idxCmp, s.areContinuous)
 
-	if err := newSegment.MergePeriod(periodStart, periodEnd, data); err != nil {
+	if err := s.mergeAddedPeriod(newSegment, periodStart, periodEnd, data); err != nil {
 		return err
 	}
 
 	if intersectFirstSegmentIdx == intersectLastSegmentIdx {
-		s.segments = slices.Insert(s.segments, intersectFirstSegmentIdx+1, newSegment)
+		s.segments.Replace(intersectFirstSegmentIdx+1, intersectFirstSegmentIdx+1, newSegment)
 		return nil
 	}
 
-	s.segments[intersectFirstSegmentIdx+1] = newSegment
-	segmentsToDelete := intersectLastSegmentIdx - intersectFirstSegmentIdx - 1
-	s.segments = slices.Delete(s.segments, intersectFirstSegmentIdx+2, intersectFirstSegmentIdx+2+segmentsToDelete)
+	s.segments.Replace(intersectFirstSegmentIdx+1, intersectLastSegmentIdx+1, newSegment)
 
 	return nil
 }
 
 func (s *Series[Data, Index]) findSegmentWhichStartsBeforeOrAt(t Index, includeContinuous bool) (_ int, contains bool) { // PeriodStart >= t
-	segmentWhichStartsLaterOrAt := sort.Search(len(s.segments), func(i int) bool {
-		return s.idxCmp(s.segments[i].PeriodStart, t) >= 0
+	segmentWhichStartsLaterOrAt := s.segments.Search(func(segment *SeriesSegment[Data, Index]) bool {
+		return s.idxCmp(segment.PeriodStart, t) >= 0
 	})
 
 	// if segmentWhichStartsLaterOrAt == 0 {
@@ -447,17 +744,17 @@ func (s *Series[Data, Index]) findSegmentWhichStartsBeforeOrAt(t Index, includeC
 	// 	return 0, true
 	// }
 
-	evenLastSegmentStartsBefore := segmentWhichStartsLaterOrAt == len(s.segments)
+	evenLastSegmentStartsBefore := segmentWhichStartsLaterOrAt == s.segments.Len()
 
 	if evenLastSegmentStartsBefore {
 		lastSegment := segmentWhichStartsLaterOrAt - 1
-		lastSegmentEnd := s.segments[lastSegment].PeriodEnd
+		lastSegmentEnd := s.segments.At(lastSegment).PeriodEnd
 		contains := s.idxCmp(t, lastSegmentEnd) <= 0 || (includeContinuous && s.areContinuous(lastSegmentEnd, t))
 		return lastSegment, contains
 	}
 
-	segmentStartsLater := s.idxCmp(s.segments[segmentWhichStartsLaterOrAt].PeriodStart, t) > 0
-	areContinuous := includeContinuous && s.areContinuous(t, s.segments[segmentWhichStartsLaterOrAt].PeriodStart)
+	segmentStartsLater := s.idxCmp(s.segments.At(segmentWhichStartsLaterOrAt).PeriodStart, t) > 0
+	areContinuous := includeContinuous && s.areContinuous(t, s.segments.At(segmentWhichStartsLaterOrAt).PeriodStart)
 	segmentStartsAt := !segmentStartsLater || areContinuous
 
 	if segmentStartsAt {
@@ -468,11 +765,25 @@ func (s *Series[Data, Index]) findSegmentWhichStartsBeforeOrAt(t Index, includeC
 	}
 
 	segment := segmentWhichStartsLaterOrAt - 1
-	segmentEnd := s.segments[segment].PeriodEnd
+	segmentEnd := s.segments.At(segment).PeriodEnd
 	contains = s.idxCmp(t, segmentEnd) <= 0 || (includeContinuous && s.areContinuous(segmentEnd, t))
 	return segment, contains
 }
 
+func (s *Series[Data, Index]) findNonEmptySegmentWhichStartsBeforeOrAt(t Index) int {
+	segmentWhichStartsLater := s.segments.SearchNonEmpty(func(segment *SeriesSegment[Data, Index]) bool {
+		return s.idxCmp(segment.PeriodStart, t) > 0
+	})
+
+	return segmentWhichStartsLater - 1
+}
+
+func (s *Series[Data, Index]) findNonEmptySegmentWhichEndsAfterOrAt(t Index) int {
+	return s.segments.SearchNonEmpty(func(segment *SeriesSegment[Data, Index]) bool {
+		return s.idxCmp(segment.PeriodEnd, t) >= 0
+	})
+}
+
 func (s *Series[Data, Index]) getSmallerIndex(idx1, idx2 Index) Index {
 	if s.idxCmp(idx1, idx2) < 0 {
 		return idx1
@@ -481,6 +792,11 @@ func (s *Series[Data, Index]) getSmallerIndex(idx1, idx2 Index) Index {
 	return idx2
 }
 
+type PeriodData[Data any, Index any] struct {
+	PeriodBounds[Index]
+	Data []Data
+}
+
 type SeriesState[Data any, Index any] struct {
 	Segments []*SeriesSegmentFields[Data, Index]
 }
@@ -0,0 +1,194 @@
+package sparse_test
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/nnikolash/go-sparse"
+	"github.com/nnikolash/go-sparse/sparsetest"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	fuzzIndexesCount = 64
+	fuzzMaxSteps     = 50
+	fuzzMaxGetLen    = 8
+)
+
+// Reference model of series over integer indexes [ 0 ; fuzzIndexesCount ).
+// Coverage is kept over half-points: index i is half-point 2*i and the gap between i and i+1 is half-point 2*i+1.
+type seriesModel struct {
+	continuous bool
+	covered    [2 * fuzzIndexesCount]bool
+	values     [fuzzIndexesCount]*sparsetest.Item
+}
+
+func (m *seriesModel) AddPeriod(periodStart, periodEnd int, data []sparsetest.Item) {
+	for i := 2 * periodStart; i <= 2*periodEnd; i++ {
+		m.covered[i] = true
+	}
+
+	for i := periodStart; i <= periodEnd; i++ {
+		m.values[i] = nil
+	}
+
+	for _, item := range data {
+		m.values[item.Idx] = &item
+	}
+}
+
+func (m *seriesModel) isCovered(halfPoint int) bool {
+	if halfPoint%2 == 1 && m.continuous {
+		return m.covered[halfPoint-1] && m.covered[halfPoint+1]
+	}
+
+	return m.covered[halfPoint]
+}
+
+func (m *seriesModel) Segments() []sparse.SeriesSegmentFields[sparsetest.Item, int] {
+	var segments []sparse.SeriesSegmentFields[sparsetest.Item, int]
+
+	for i := 0; i < 2*fuzzIndexesCount-1; {
+		if !m.isCovered(i) {
+			i++
+			continue
+		}
+
+		start := i
+		for i < 2*fuzzIndexesCount-1 && m.isCovered(i) {
+			i++
+		}
+
+		segment := sparse.SeriesSegmentFields[sparsetest.Item, int]{
+			PeriodBounds: sparse.PeriodBounds[int]{PeriodStart: start / 2, PeriodEnd: (i - 1) / 2},
+			Empty:        true,
+		}
+
+		for idx := segment.PeriodStart; idx <= segment.PeriodEnd; idx++ {
+			if m.values[idx] != nil {
+				segment.Empty = false
+			}
+		}
+
+		segments = append(segments, segment)
+	}
+
+	return segments
+}
+
+// Returns data of the period or false if the period is not fully covered by single segment.
+func (m *seriesModel) Get(periodStart, periodEnd int) ([]sparsetest.Item, bool) {
+	for i := 2 * periodStart; i <= 2*periodEnd; i++ {
+		if !m.isCovered(i) {
+			return nil, false
+		}
+	}
+
+	var data []sparsetest.Item
+	for idx := periodStart; idx <= periodEnd; idx++ {
+		if m.values[idx] != nil {
+			data = append(data, *m.values[idx])
+		}
+	}
+
+	return data, true
+}
+
+func FuzzSeries_AddPeriod(f *testing.F) {
+	addSeriesFuzzSeeds(f)
+
+	f.Fuzz(func(t *testing.T, ops []byte) {
+		fuzzSeriesAddPeriod(t, ops, false)
+	})
+}
+
+func FuzzSeries_AddPeriodContinuous(f *testing.F) {
+	addSeriesFuzzSeeds(f)
+
+	f.Fuzz(func(t *testing.T, ops []byte) {
+		fuzzSeriesAddPeriod(t, ops, true)
+	})
+}
+
+func addSeriesFuzzSeeds(f *testing.F) {
+	f.Add([]byte{10, 5, 0xFF})
+	f.Add([]byte{10, 5, 0x01, 20, 5, 0x10, 14, 8, 0x00})
+	f.Add([]byte{30, 3, 0x05, 10, 3, 0x05, 20, 3, 0x05, 5, 40, 0x81})
+	f.Add([]byte{10, 4, 0x11, 15, 4, 0x11, 14, 0, 0x00, 20, 4, 0x00, 12, 9, 0x42})
+	f.Add([]byte{40, 2, 0x03, 20, 2, 0x03, 0, 2, 0x03, 3, 16, 0x00, 23, 16, 0xFF, 50, 10, 0x00, 1, 60, 0x00})
+}
+
+// Each operation is encoded by 3 bytes: period start, period length and bit mask of indexes, which have data.
+func fuzzSeriesAddPeriod(t *testing.T, ops []byte, continuous bool) {
+	var areContinuous func(smaller, bigger int) bool
+	if continuous {
+		areContinuous = func(smaller, bigger int) bool { return bigger-smaller == 1 }
+	}
+
+	series := sparse.NewSeries(sparse.NewArrayData, sparsetest.ItemIdx, sparsetest.ItemIdxCmp, areContinuous)
+	model := &seriesModel{continuous: continuous}
+
+	for step := 0; step < fuzzMaxSteps && len(ops) >= 3; step++ {
+		periodStart := int(ops[0]) % fuzzIndexesCount
+		periodEnd := min(periodStart+int(ops[1])%16, fuzzIndexesCount-1)
+		mask := ops[2]
+		ops = ops[3:]
+
+		var data []sparsetest.Item
+		for idx := periodStart; idx <= periodEnd; idx++ {
+			if mask&(1<<((idx-periodStart)%8)) != 0 {
+				data = append(data, sparsetest.Item{Idx: idx, Val: float64(step)})
+			}
+		}
+
+		desc := fmt.Sprintf("step %v: AddPeriod(%v, %v, %v)", step, periodStart, periodEnd, data)
+
+		require.NoError(t, series.AddPeriod(periodStart, periodEnd, data), desc)
+		model.AddPeriod(periodStart, periodEnd, data)
+
+		checkSeriesMatchesModel(t, series, model, desc)
+	}
+}
+
+func checkSeriesMatchesModel(t *testing.T, series *sparse.Series[sparsetest.Item, int], model *seriesModel, desc string) {
+	require.NoError(t, series.Validate(), desc)
+
+	expectedSegments := model.Segments()
+	segments := series.Segments()
+
+	require.Len(t, segments, len(expectedSegments), "%v\n%v", desc, series.SegmentsString())
+	for i, segment := range segments {
+		require.Equal(t, expectedSegments[i].PeriodBounds, segment.PeriodBounds, "%v: segment %v\n%v", desc, i, series.SegmentsString())
+		require.Equal(t, expectedSegments[i].Empty, segment.Empty, "%v: segment %v\n%v", desc, i, series.SegmentsString())
+	}
+
+	periods := make([]sparse.PeriodBounds[int], 0, fuzzIndexesCount*fuzzMaxGetLen+len(expectedSegments))
+	for periodStart := 0; periodStart < fuzzIndexesCount; periodStart++ {
+		for periodEnd := periodStart; periodEnd < min(periodStart+fuzzMaxGetLen, fuzzIndexesCount); periodEnd++ {
+			periods = append(periods, sparse.PeriodBounds[int]{PeriodStart: periodStart, PeriodEnd: periodEnd})
+		}
+	}
+	for _, segment := range expectedSegments {
+		periods = append(periods, segment.PeriodBounds)
+	}
+
+	for _, period := range periods {
+		expected, ok := model.Get(period.PeriodStart, period.PeriodEnd)
+
+		data, err := series.Get(period.PeriodStart, period.PeriodEnd)
+		if !ok {
+			if err == nil {
+				t.Fatalf("%v: Get(%v, %v): expected error, got %v\n%v", desc, period.PeriodStart, period.PeriodEnd, data, series.SegmentsString())
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Fatalf("%v: Get(%v, %v): %v\n%v", desc, period.PeriodStart, period.PeriodEnd, err, series.SegmentsString())
+		}
+		if !slices.Equal(expected, data) {
+			t.Fatalf("%v: Get(%v, %v): expected %v, got %v\n%v", desc, period.PeriodStart, period.PeriodEnd, expected, data, series.SegmentsString())
+		}
+	}
+}
@@ -2,6 +2,7 @@ package sparse_test
 
 import (
 	"fmt"
+	"math/rand/v2"
 	"testing"
 
 	"github.com/nnikolash/go-sparse"
@@ -1925,3 +1926,370 @@ func TestSparseSeries_OverwriteInRangeEnd(t *testing.T) {
 	_, err = series.Get(10, 70)
 	require.Error(t, err)
 }
+
+func TestSparseSeries_AddPeriodReplacesDataWithinPeriod(t *testing.T) {
+	t.Parallel()
+
+	series := intSparseSeries()
+
+	err := series.AddPeriod(0, 10, []int{2, 5, 8})
+	require.NoError(t, err)
+
+	// New data does not reach period bounds, but old data between them is replaced anyway
+	err = series.AddPeriod(3, 7, []int{4})
+	require.NoError(t, err)
+	res, err := series.Get(0, 10)
+	require.NoError(t, err)
+	require.Equal(t, []int{2, 4, 8}, res)
+
+	err = series.AddPeriod(1, 9, nil)
+	require.NoError(t, err)
+	res, err = series.Get(0, 10)
+	require.NoError(t, err)
+	require.Nil(t, res)
+}
+
+func TestSparseSeries_AddEmptyPeriodUpdatesEmptyFlag(t *testing.T) {
+	t.Parallel()
+
+	series := intSparseSeries()
+
+	err := series.AddPeriod(0, 10, []int{5})
+	require.NoError(t, err)
+	require.False(t, series.GetPeriod(0, 10).Empty)
+
+	err = series.AddPeriod(0, 10, nil)
+	require.NoError(t, err)
+	require.True(t, series.GetPeriod(0, 10).Empty)
+	require.Nil(t, series.GetPeriodClosestFromStart(10, true))
+}
+
+func TestSparseSeries_AddPeriodContinuousWithBothNeighbours(t *testing.T) {
+	t.Parallel()
+
+	series := intSparseSeries()
+
+	err := series.AddPeriod(0, 4, []int{1})
+	require.NoError(t, err)
+	err = series.AddPeriod(6, 10, []int{7})
+	require.NoError(t, err)
+
+	// Period [5; 5] is continuous with both segments, so all three are joined
+	err = series.AddPeriod(5, 5, []int{5})
+	require.NoError(t, err)
+	require.Len(t, series.Segments(), 1)
+
+	res, err := series.Get(0, 10)
+	require.NoError(t, err)
+	require.Equal(t, []int{1, 5, 7}, res)
+}
+
+func TestSparseSeries_DeletePeriod(t *testing.T) {
+	t.Parallel()
+
+	series := intSparseSeries()
+
+	err := series.AddData([]int{10, 20, 30})
+	require.NoError(t, err)
+	err = series.AddData([]int{50, 60, 70})
+	require.NoError(t, err)
+	err = series.AddData([]int{90, 100, 110})
+	require.NoError(t, err)
+
+	err = series.DeletePeriod(40, 45)
+	require.NoError(t, err)
+	println(0, series.SegmentsString())
+	require.Len(t, series.Segments(), 3)
+
+	// Remaining parts are covered only up to their items closest to the period
+	err = series.DeletePeriod(55, 65)
+	require.NoError(t, err)
+	println(0, series.SegmentsString())
+	require.Len(t, series.Segments(), 4)
+	res, err := series.Get(50, 50)
+	require.NoError(t, err)
+	require.Equal(t, []int{50}, res)
+	res, err = series.Get(70, 70)
+	require.NoError(t, err)
+	require.Equal(t, []int{70}, res)
+	_, err = series.Get(50, 55)
+	require.Error(t, err)
+	_, err = series.Get(65, 70)
+	require.Error(t, err)
+
+	err = series.DeletePeriod(25, 95)
+	require.NoError(t, err)
+	println(0, series.SegmentsString())
+	require.Len(t, series.Segments(), 2)
+	res, err = series.Get(10, 20)
+	require.NoError(t, err)
+	require.Equal(t, []int{10, 20}, res)
+	res, err = series.Get(100, 110)
+	require.NoError(t, err)
+	require.Equal(t, []int{100, 110}, res)
+	_, err = series.Get(10, 25)
+	require.Error(t, err)
+	_, err = series.Get(95, 110)
+	require.Error(t, err)
+
+	err = series.DeletePeriod(0, 10)
+	require.NoError(t, err)
+	println(0, series.SegmentsString())
+	res, err = series.Get(20, 20)
+	require.NoError(t, err)
+	require.Equal(t, []int{20}, res)
+	_, err = series.Get(10, 20)
+	require.Error(t, err)
+
+	err = series.DeletePeriod(20, 20)
+	require.NoError(t, err)
+	println(0, series.SegmentsString())
+	require.Len(t, series.Segments(), 1)
+	_, err = series.Get(20, 20)
+	require.Error(t, err)
+
+	err = series.AddData([]int{30, 40})
+	require.NoError(t, err)
+	println(0, series.SegmentsString())
+	res, err = series.Get(30, 40)
+	require.NoError(t, err)
+	require.Equal(t, []int{30, 40}, res)
+	_, err = series.Get(25, 30)
+	require.Error(t, err)
+
+	err = series.DeletePeriod(0, 200)
+	require.NoError(t, err)
+	require.Empty(t, series.Segments())
+}
+
+func TestSparseSeries_DeletePeriodBounds(t *testing.T) {
+	t.Parallel()
+
+	series := intSparseSeries()
+
+	err := series.AddPeriod(0, 100, []int{0, 10, 20, 30, 40, 50, 60, 70, 80, 90, 100})
+	require.NoError(t, err)
+
+	// Items at both bounds are deleted
+	err = series.DeletePeriod(20, 40)
+	require.NoError(t, err)
+	res, err := series.Get(0, 10)
+	require.NoError(t, err)
+	require.Equal(t, []int{0, 10}, res)
+	res, err = series.Get(50, 100)
+	require.NoError(t, err)
+	require.Equal(t, []int{50, 60, 70, 80, 90, 100}, res)
+	require.Equal(t, []sparse.PeriodBounds[int]{{PeriodStart: 10, PeriodEnd: 50}}, series.MissingPeriods(0, 100))
+
+	// Segment starts at the period start
+	err = series.DeletePeriod(50, 50)
+	require.NoError(t, err)
+	require.Equal(t, 60, series.GetPeriodClosestFromEnd(45, false).PeriodStart)
+
+	// Single point inside the segment
+	err = series.DeletePeriod(80, 80)
+	require.NoError(t, err)
+	res, err = series.Get(60, 70)
+	require.NoError(t, err)
+	require.Equal(t, []int{60, 70}, res)
+	res, err = series.Get(90, 100)
+	require.NoError(t, err)
+	require.Equal(t, []int{90, 100}, res)
+	require.Nil(t, series.GetPeriod(80, 80))
+
+	// Segment ends at the period end
+	err = series.DeletePeriod(95, 100)
+	require.NoError(t, err)
+	require.Equal(t, []sparse.PeriodBounds[int]{{PeriodStart: 0, PeriodEnd: 10}, {PeriodStart: 60, PeriodEnd: 70}, {PeriodStart: 90, PeriodEnd: 90}}, boundsOf(series.Segments()))
+}
+
+func boundsOf(segments []*sparse.SeriesSegment[int, int]) []sparse.PeriodBounds[int] {
+	bounds := make([]sparse.PeriodBounds[int], 0, len(segments))
+	for _, segment := range segments {
+		bounds = append(bounds, segment.PeriodBounds)
+	}
+
+	return bounds
+}
+
+func TestSparseSeries_DeletePeriodEmptyFlag(t *testing.T) {
+	t.Parallel()
+
+	series := intSparseSeries()
+
+	err := series.AddPeriod(0, 100, []int{10, 90})
+	require.NoError(t, err)
+
+	err = series.DeletePeriod(5, 95)
+	require.NoError(t, err)
+	println(0, series.SegmentsString())
+	segments := series.Segments()
+	require.Len(t, segments, 2)
+	require.True(t, segments[0].Empty)
+	require.Equal(t, 0, segments[0].PeriodStart)
+	require.Equal(t, 0, segments[0].PeriodEnd)
+	require.True(t, segments[1].Empty)
+	require.Equal(t, 100, segments[1].PeriodStart)
+	require.Equal(t, 100, segments[1].PeriodEnd)
+
+	err = series.DeletePeriod(3, 3)
+	require.NoError(t, err)
+	require.Len(t, series.Segments(), 2)
+}
+
+func TestSparseSeries_DeletePeriodFromEmptySegment(t *testing.T) {
+	t.Parallel()
+
+	series := intSparseSeries()
+	series.SetValidateOnChange(true)
+
+	require.NoError(t, series.AddPeriod(0, 100, nil))
+	require.NoError(t, series.DeletePeriod(1, 99))
+
+	// Neighbouring indexes of deleted period are unknown, so only bounds of the segment stay covered
+	require.Equal(t, []sparse.PeriodBounds[int]{{PeriodStart: 0, PeriodEnd: 0}, {PeriodStart: 100, PeriodEnd: 100}}, boundsOf(series.Segments()))
+	require.True(t, series.Segments()[0].Empty)
+	require.True(t, series.Segments()[1].Empty)
+	// Missing period touches the remaining segments
+	require.Equal(t, []sparse.PeriodBounds[int]{{PeriodStart: 0, PeriodEnd: 100}}, series.MissingPeriods(0, 100))
+	require.Empty(t, series.MissingPeriods(100, 100))
+}
+
+func TestSparseSeries_MissingPeriods(t *testing.T) {
+	t.Parallel()
+
+	series := intSparseSeries()
+
+	require.Equal(t, []sparse.PeriodBounds[int]{{PeriodStart: 0, PeriodEnd: 100}}, series.MissingPeriods(0, 100))
+
+	err := series.AddData([]int{10, 20, 30})
+	require.NoError(t, err)
+	err = series.AddData([]int{50, 60})
+	require.NoError(t, err)
+	err = series.AddData([]int{61, 70})
+	require.NoError(t, err)
+	err = series.AddPeriod(71, 80, nil)
+	require.NoError(t, err)
+	err = series.AddData([]int{82, 90})
+	require.NoError(t, err)
+	println(0, series.SegmentsString())
+
+	require.Empty(t, series.MissingPeriods(10, 30))
+	require.Empty(t, series.MissingPeriods(15, 25))
+	require.Empty(t, series.MissingPeriods(50, 80))
+	require.Nil(t, series.MissingPeriods(30, 10))
+
+	require.Equal(t, []sparse.PeriodBounds[int]{
+		{PeriodStart: 0, PeriodEnd: 10},
+	}, series.MissingPeriods(0, 20))
+
+	require.Equal(t, []sparse.PeriodBounds[int]{
+		{PeriodStart: 30, PeriodEnd: 50},
+	}, series.MissingPeriods(20, 60))
+
+	require.Equal(t, []sparse.PeriodBounds[int]{
+		{PeriodStart: 35, PeriodEnd: 45},
+	}, series.MissingPeriods(35, 45))
+
+	require.Equal(t, []sparse.PeriodBounds[int]{
+		{PeriodStart: 0, PeriodEnd: 10},
+		{PeriodStart: 30, PeriodEnd: 50},
+		{PeriodStart: 80, PeriodEnd: 82},
+		{PeriodStart: 90, PeriodEnd: 100},
+	}, series.MissingPeriods(0, 100))
+
+	require.Equal(t, []sparse.PeriodBounds[int]{
+		{PeriodStart: 30, PeriodEnd: 50},
+		{PeriodStart: 80, PeriodEnd: 82},
+	}, series.MissingPeriods(25, 85))
+
+	require.Equal(t, []sparse.PeriodBounds[int]{
+		{PeriodStart: 90, PeriodEnd: 91},
+	}, series.MissingPeriods(90, 91))
+
+	require.Equal(t, []sparse.PeriodBounds[int]{
+		{PeriodStart: 95, PeriodEnd: 95},
+	}, series.MissingPeriods(95, 95))
+}
+
+func TestSparseSeries_GetAvailable(t *testing.T) {
+	t.Parallel()
+
+	series := intSparseSeries()
+
+	available, missing, err := series.GetAvailable(0, 100)
+	require.NoError(t, err)
+	require.Empty(t, available)
+	require.Equal(t, []sparse.PeriodBounds[int]{{PeriodStart: 0, PeriodEnd: 100}}, missing)
+
+	err = series.AddData([]int{10, 20, 30})
+	require.NoError(t, err)
+	err = series.AddPeriod(40, 50, nil)
+	require.NoError(t, err)
+	err = series.AddData([]int{60, 70, 80})
+	require.NoError(t, err)
+	println(0, series.SegmentsString())
+
+	available, missing, err = series.GetAvailable(15, 75)
+	require.NoError(t, err)
+	require.Equal(t, []sparse.PeriodData[int, int]{
+		{PeriodBounds: sparse.PeriodBounds[int]{PeriodStart: 15, PeriodEnd: 30}, Data: []int{20, 30}},
+		{PeriodBounds: sparse.PeriodBounds[int]{PeriodStart: 40, PeriodEnd: 50}, Data: []int{}},
+		{PeriodBounds: sparse.PeriodBounds[int]{PeriodStart: 60, PeriodEnd: 75}, Data: []int{60, 70}},
+	}, available)
+	require.Equal(t, []sparse.PeriodBounds[int]{
+		{PeriodStart: 30, PeriodEnd: 40},
+		{PeriodStart: 50, PeriodEnd: 60},
+	}, missing)
+
+	available, missing, err = series.GetAvailable(20, 30)
+	require.NoError(t, err)
+	require.Equal(t, []sparse.PeriodData[int, int]{
+		{PeriodBounds: sparse.PeriodBounds[int]{PeriodStart: 20, PeriodEnd: 30}, Data: []int{20, 30}},
+	}, available)
+	require.Empty(t, missing)
+
+	available, missing, err = series.GetAvailable(32, 38)
+	require.NoError(t, err)
+	require.Empty(t, available)
+	require.Equal(t, []sparse.PeriodBounds[int]{{PeriodStart: 32, PeriodEnd: 38}}, missing)
+
+	_, _, err = series.GetAvailable(38, 32)
+	require.Error(t, err)
+}
+
+func TestSparseSeries_ManySegments(t *testing.T) {
+	t.Parallel()
+
+	const count = 10000
+
+	series := intSparseSeries()
+
+	for _, i := range rand.Perm(count) {
+		err := series.AddPeriod(i*10, i*10+5, []int{i*10 + 1})
+		require.NoError(t, err)
+	}
+
+	segments := series.Segments()
+	require.Len(t, segments, count)
+	for i, segment := range segments {
+		require.Equal(t, i*10, segment.PeriodStart)
+		require.Equal(t, i*10+5, segment.PeriodEnd)
+	}
+
+	for _, i := range rand.Perm(count)[:count/2] {
+		err := series.AddPeriod(i*10+5, i*10+10, nil)
+		require.NoError(t, err)
+	}
+
+	for i := 0; i < count; i++ {
+		data, err := series.Get(i*10, i*10+5)
+		require.NoError(t, err)
+		require.Equal(t, []int{i*10 + 1}, data)
+	}
+
+	segment := series.GetPeriodClosestFromStart(5003, true)
+	require.NotNil(t, segment)
+	require.LessOrEqual(t, segment.PeriodStart, 5000)
+	require.GreaterOrEqual(t, segment.PeriodEnd, 5005)
+}
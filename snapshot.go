@@ -0,0 +1,110 @@
+package sparse
+
+import "iter"
+
+// Immutable view of series at the moment of its creation.
+// Segments returned by snapshot must not be modified.
+type SeriesSnapshot[Data any, Index any] struct {
+	series *Series[Data, Index]
+}
+
+// Creates snapshot of the series. Storages, which implement SnapshotableSeriesData, share their data with snapshot.
+// Data of other storages is copied into ArrayData.
+func (s *Series[Data, Index]) Snapshot() (*SeriesSnapshot[Data, Index], error) {
+	segments := make([]*SeriesSegment[Data, Index], 0, s.segments.Len())
+
+	for segment := range s.segments.Values(0) {
+		data, err := s.snapshotData(segment)
+		if err != nil {
+			return nil, err
+		}
+
+		segmentCopy := NewSeriesSegment(s.dataFactory, s.getIdx, s.idxCmp, s.areContinuous)
+		segmentCopy.Restore(&SeriesSegmentFields[Data, Index]{
+			PeriodBounds: segment.PeriodBounds,
+			Data:         data,
+			Empty:        segment.Empty,
+		})
+
+		segments = append(segments, segmentCopy)
+	}
+
+	series := NewSeries(s.dataFactory, s.getIdx, s.idxCmp, s.areContinuous)
+	series.segments = newSegmentTree(segments...)
+	series.codec = s.codec
+
+	return &SeriesSnapshot[Data, Index]{series: series}, nil
+}
+
+func (s *Series[Data, Index]) snapshotData(segment *SeriesSegment[Data, Index]) (SeriesData[Data, Index], error) {
+	if snapshotable, ok := segment.Data.(SnapshotableSeriesData[Data, Index]); ok {
+		return snapshotable.Snapshot()
+	}
+
+	data, err := segment.GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	return NewArrayData(s.getIdx, s.idxCmp, segment.PeriodStart, segment.PeriodEnd, data)
+}
+
+func (s *SeriesSnapshot[Data, Index]) Segments() []*SeriesSegment[Data, Index] {
+	return s.series.Segments()
+}
+
+func (s *SeriesSnapshot[Data, Index]) SegmentsString() string {
+	return s.series.SegmentsString()
+}
+
+func (s *SeriesSnapshot[Data, Index]) GetSegment(t Index) *SeriesSegment[Data, Index] {
+	return s.series.GetSegment(t)
+}
+
+func (s *SeriesSnapshot[Data, Index]) Get(periodStart, periodEnd Index) ([]Data, error) {
+	return s.series.Get(periodStart, periodEnd)
+}
+
+func (s *SeriesSnapshot[Data, Index]) All(periodStart, periodEnd Index) (iter.Seq2[Index, Data], error) {
+	return s.series.All(periodStart, periodEnd)
+}
+
+func (s *SeriesSnapshot[Data, Index]) Backward(periodStart, periodEnd Index) (iter.Seq2[Index, Data], error) {
+	return s.series.Backward(periodStart, periodEnd)
+}
+
+func (s *SeriesSnapshot[Data, Index]) SegmentsBetween(periodStart, periodEnd Index, nonEmptyOnly bool) iter.Seq[*SeriesSegment[Data, Index]] {
+	return s.series.SegmentsBetween(periodStart, periodEnd, nonEmptyOnly)
+}
+
+func (s *SeriesSnapshot[Data, Index]) GetAvailable(periodStart, periodEnd Index) (available []PeriodData[Data, Index], missing []PeriodBounds[Index], _ error) {
+	return s.series.GetAvailable(periodStart, periodEnd)
+}
+
+func (s *SeriesSnapshot[Data, Index]) GetPeriod(periodStart, periodEnd Index) *SeriesSegment[Data, Index] {
+	return s.series.GetPeriod(periodStart, periodEnd)
+}
+
+func (s *SeriesSnapshot[Data, Index]) GetPeriodClosestFromStart(t Index, nonEmpty bool) *SeriesSegment[Data, Index] {
+	return s.series.GetPeriodClosestFromStart(t, nonEmpty)
+}
+
+func (s *SeriesSnapshot[Data, Index]) GetPeriodClosestFromEnd(t Index, nonEmpty bool) *SeriesSegment[Data, Index] {
+	return s.series.GetPeriodClosestFromEnd(t, nonEmpty)
+}
+
+func (s *SeriesSnapshot[Data, Index]) MissingPeriods(periodStart, periodEnd Index) []PeriodBounds[Index] {
+	return s.series.MissingPeriods(periodStart, periodEnd)
+}
+
+func (s *SeriesSnapshot[Data, Index]) Validate() error {
+	return s.series.Validate()
+}
+
+func (s *SeriesSnapshot[Data, Index]) MarshalJSON() ([]byte, error) {
+	return s.series.MarshalJSON()
+}
+
+func (s *SeriesSnapshot[Data, Index]) MarshalBinary() ([]byte, error) {
+	return s.series.MarshalBinary()
+}
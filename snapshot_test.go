@@ -0,0 +1,125 @@
+package sparse_test
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/nnikolash/go-sparse"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSeriesSnapshot_Immutable(t *testing.T) {
+	t.Parallel()
+
+	series := intSparseSeries()
+
+	err := series.AddData([]int{10, 20, 30})
+	require.NoError(t, err)
+	err = series.AddData([]int{50, 60, 70})
+	require.NoError(t, err)
+
+	snapshot, err := series.Snapshot()
+	require.NoError(t, err)
+
+	err = series.AddData([]int{25, 35, 45, 55})
+	require.NoError(t, err)
+	err = series.DeletePeriod(65, 100)
+	require.NoError(t, err)
+	err = series.AddData([]int{0, 10})
+	require.NoError(t, err)
+	println(0, series.SegmentsString())
+	println(0, snapshot.SegmentsString())
+
+	res, err := series.Get(0, 60)
+	require.NoError(t, err)
+	require.Equal(t, []int{0, 10, 20, 25, 35, 45, 55, 60}, res)
+
+	require.Len(t, snapshot.Segments(), 2)
+	res, err = snapshot.Get(10, 30)
+	require.NoError(t, err)
+	require.Equal(t, []int{10, 20, 30}, res)
+	res, err = snapshot.Get(50, 70)
+	require.NoError(t, err)
+	require.Equal(t, []int{50, 60, 70}, res)
+	_, err = snapshot.Get(0, 70)
+	require.Error(t, err)
+	require.Equal(t, []sparse.PeriodBounds[int]{{PeriodStart: 30, PeriodEnd: 50}}, snapshot.MissingPeriods(10, 70))
+}
+
+// Hides all optional interfaces of wrapped storage.
+type notSnapshotableData[Data any, Index any] struct {
+	sparse.SeriesData[Data, Index]
+}
+
+func TestSeriesSnapshot_NotSnapshotableData(t *testing.T) {
+	t.Parallel()
+
+	series := sparse.NewSeries[int, int](
+		func(getIdx func(data *int) int, idxCmp func(idx1, idx2 int) int, periodStart, periodEnd int, data []int) (sparse.SeriesData[int, int], error) {
+			storage, err := sparse.NewArrayData(getIdx, idxCmp, periodStart, periodEnd, data)
+			return notSnapshotableData[int, int]{storage}, err
+		},
+		func(data *int) int { return *data },
+		func(idx1, idx2 int) int { return idx1 - idx2 },
+		nil,
+	)
+
+	err := series.AddPeriod(0, 40, []int{10, 20, 30})
+	require.NoError(t, err)
+
+	snapshot, err := series.Snapshot()
+	require.NoError(t, err)
+
+	err = series.AddPeriod(0, 40, []int{15})
+	require.NoError(t, err)
+
+	res, err := snapshot.Get(0, 40)
+	require.NoError(t, err)
+	require.Equal(t, []int{10, 20, 30}, res)
+	require.Equal(t, 0, snapshot.Segments()[0].PeriodStart)
+	require.Equal(t, 40, snapshot.Segments()[0].PeriodEnd)
+}
+
+func TestSeriesSnapshot_Concurrent(t *testing.T) {
+	t.Parallel()
+
+	series := sparse.NewConcurrentSeries[int, int](
+		sparse.NewArrayData,
+		func(data *int) int { return *data },
+		func(idx1, idx2 int) int { return idx1 - idx2 },
+		func(smaller, bigger int) bool { return bigger-smaller == 1 },
+	)
+
+	err := series.AddData([]int{0, 1, 2, 3, 4, 5})
+	require.NoError(t, err)
+
+	snapshot, err := series.Snapshot()
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			assert.NoError(t, series.AddData([]int{i % 5, i%5 + 1}))
+			assert.NoError(t, series.AddData([]int{i + 6}))
+		}
+	}()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			res, err := snapshot.Get(0, 5)
+			assert.NoError(t, err)
+			assert.Equal(t, []int{0, 1, 2, 3, 4, 5}, res)
+
+			segment := snapshot.GetPeriodClosestFromEnd(3, true)
+			assert.Equal(t, 5, segment.PeriodEnd)
+		}
+	}()
+
+	wg.Wait()
+}
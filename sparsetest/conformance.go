@@ -0,0 +1,292 @@
+// Package sparsetest contains helpers for testing custom implementations of sparse storages.
+package sparsetest
+
+import (
+	"testing"
+
+	"github.com/nnikolash/go-sparse"
+	"github.com/stretchr/testify/require"
+)
+
+// Data type used by conformance tests.
+type Item struct {
+	Idx int
+	Val float64
+}
+
+func ItemIdx(item *Item) int {
+	return item.Idx
+}
+
+func ItemIdxCmp(idx1, idx2 int) int {
+	return idx1 - idx2
+}
+
+// Creates items for provided indexes. Value of each item is equal to its index multiplied by valMult.
+func Items(valMult float64, idxs ...int) []Item {
+	items := make([]Item, 0, len(idxs))
+	for _, idx := range idxs {
+		items = append(items, Item{Idx: idx, Val: float64(idx) * valMult})
+	}
+
+	return items
+}
+
+// Describes data and indexes used by conformance tests. Tests operate on integer positions in range [ -100 ; 200 ],
+// which are converted into indexes and items of the tested storage.
+type Fixture[Data any, Index any] struct {
+	GetIdx func(data *Data) Index
+	IdxCmp func(idx1, idx2 Index) int
+	// Converts position into index. Bigger positions must be converted into bigger indexes.
+	Index func(pos int) Index
+	// Creates item with index of the position. Items of different versions at the same position must not be equal.
+	// Versions start from 1.
+	Item func(pos int, version int) Data
+}
+
+// Fixture of Item type, which has index equal to position and value equal to position multiplied by version.
+func ItemFixture() Fixture[Item, int] {
+	return Fixture[Item, int]{
+		GetIdx: ItemIdx,
+		IdxCmp: ItemIdxCmp,
+		Index:  func(pos int) int { return pos },
+		Item:   func(pos int, version int) Item { return Item{Idx: pos, Val: float64(pos * version)} },
+	}
+}
+
+func (f Fixture[Data, Index]) items(version int, poss ...int) []Data {
+	items := make([]Data, 0, len(poss))
+	for _, pos := range poss {
+		items = append(items, f.Item(pos, version))
+	}
+
+	return items
+}
+
+func (f Fixture[Data, Index]) itemPtr(pos int, version int) *Data {
+	item := f.Item(pos, version)
+	return &item
+}
+
+// Checks that storage created by factory behaves the way Series expects it to.
+// Optional interfaces IterableSeriesData and SnapshotableSeriesData are checked if storage implements them.
+func RunSeriesDataConformance[Data any, Index any](t *testing.T, factory sparse.SeriesDataFactory[Data, Index], f Fixture[Data, Index]) {
+	newData := func(t *testing.T, periodStart, periodEnd int, data []Data) sparse.SeriesData[Data, Index] {
+		s, err := factory(f.GetIdx, f.IdxCmp, f.Index(periodStart), f.Index(periodEnd), data)
+		require.NoError(t, err)
+		require.NotNil(t, s)
+		return s
+	}
+
+	get := func(t *testing.T, s sparse.SeriesData[Data, Index], periodStart, periodEnd int) []Data {
+		data, err := s.Get(f.Index(periodStart), f.Index(periodEnd))
+		require.NoError(t, err)
+		return data
+	}
+
+	getEndOpen := func(t *testing.T, s sparse.SeriesData[Data, Index], periodStart, periodEnd int) []Data {
+		data, err := s.GetEndOpen(f.Index(periodStart), f.Index(periodEnd))
+		require.NoError(t, err)
+		return data
+	}
+
+	first := func(t *testing.T, s sparse.SeriesData[Data, Index], periodStart int) *Data {
+		data, err := s.First(f.Index(periodStart))
+		require.NoError(t, err)
+		return data
+	}
+
+	last := func(t *testing.T, s sparse.SeriesData[Data, Index], periodEnd int) *Data {
+		data, err := s.Last(f.Index(periodEnd))
+		require.NoError(t, err)
+		return data
+	}
+
+	del := func(t *testing.T, s sparse.SeriesData[Data, Index], periodStart, periodEnd int) {
+		require.NoError(t, s.Delete(f.Index(periodStart), f.Index(periodEnd)))
+	}
+
+	t.Run("Empty", func(t *testing.T) {
+		s := newData(t, 0, 100, nil)
+
+		require.Empty(t, get(t, s, 0, 100))
+		require.Empty(t, getEndOpen(t, s, 0, 100))
+
+		require.Nil(t, first(t, s, 0))
+		require.Nil(t, last(t, s, 100))
+
+		require.NoError(t, s.Merge(nil))
+		require.Empty(t, get(t, s, 0, 100))
+
+		del(t, s, 0, 100)
+		require.Empty(t, get(t, s, 0, 100))
+	})
+
+	t.Run("InitialData", func(t *testing.T) {
+		s := newData(t, 0, 100, f.items(1, 10, 20, 30))
+
+		require.Equal(t, f.items(1, 10, 20, 30), get(t, s, 0, 100))
+
+		require.Equal(t, f.itemPtr(10, 1), first(t, s, 0))
+
+		require.Equal(t, f.itemPtr(30, 1), last(t, s, 100))
+	})
+
+	t.Run("Bounds", func(t *testing.T) {
+		s := newData(t, 0, 100, f.items(1, 10, 20, 30, 40))
+
+		require.Equal(t, f.items(1, 20, 30), get(t, s, 20, 30))
+		require.Equal(t, f.items(1, 20, 30), get(t, s, 15, 35))
+		require.Equal(t, f.items(1, 20), getEndOpen(t, s, 20, 30))
+		require.Equal(t, f.items(1, 20, 30), getEndOpen(t, s, 15, 35))
+		require.Equal(t, f.items(1, 10), get(t, s, 10, 10))
+		require.Empty(t, getEndOpen(t, s, 10, 10))
+		require.Equal(t, f.items(1, 40), get(t, s, 40, 40))
+		require.Empty(t, get(t, s, 11, 19))
+		require.Empty(t, get(t, s, 0, 9))
+		require.Empty(t, get(t, s, 41, 100))
+		require.Equal(t, f.items(1, 10, 20, 30, 40), get(t, s, -100, 200))
+	})
+
+	t.Run("MergeSorted", func(t *testing.T) {
+		s := newData(t, 0, 100, f.items(1, 40, 50))
+
+		require.NoError(t, s.Merge(f.items(1, 10, 20)))
+		require.NoError(t, s.Merge(f.items(1, 70, 80)))
+		require.NoError(t, s.Merge(f.items(1, 60)))
+		require.NoError(t, s.Merge(f.items(1, 30)))
+
+		require.Equal(t, f.items(1, 10, 20, 30, 40, 50, 60, 70, 80), get(t, s, 0, 100))
+
+		require.Equal(t, f.itemPtr(10, 1), first(t, s, 0))
+
+		require.Equal(t, f.itemPtr(80, 1), last(t, s, 100))
+	})
+
+	t.Run("MergeOverwrite", func(t *testing.T) {
+		s := newData(t, 0, 100, f.items(1, 10, 20, 30, 40, 50))
+
+		// Old data within extent of new data is replaced by new data, even if indexes do not match.
+		require.NoError(t, s.Merge(f.items(2, 20, 25, 40)))
+		require.Equal(t, append(append(f.items(1, 10), f.items(2, 20, 25, 40)...), f.items(1, 50)...), get(t, s, 0, 100))
+
+		require.NoError(t, s.Merge(f.items(3, 5, 50)))
+		require.Equal(t, f.items(3, 5, 50), get(t, s, 0, 100))
+
+		require.NoError(t, s.Merge(f.items(4, 50, 60)))
+		require.Equal(t, append(f.items(3, 5), f.items(4, 50, 60)...), get(t, s, 0, 100))
+	})
+
+	t.Run("MergeDoesNotRetainInput", func(t *testing.T) {
+		s := newData(t, 0, 100, nil)
+
+		data := f.items(1, 10, 20)
+		require.NoError(t, s.Merge(data))
+		data[0] = f.Item(10, 2)
+
+		require.Equal(t, f.items(1, 10, 20), get(t, s, 0, 100))
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		s := newData(t, 0, 100, f.items(1, 10, 20, 30, 40, 50))
+
+		del(t, s, 20, 30)
+		require.Equal(t, f.items(1, 10, 40, 50), get(t, s, 0, 100))
+
+		del(t, s, 41, 49)
+		require.Equal(t, f.items(1, 10, 40, 50), get(t, s, 0, 100))
+
+		del(t, s, 50, 100)
+		require.Equal(t, f.items(1, 10, 40), get(t, s, 0, 100))
+
+		del(t, s, 0, 10)
+		require.Equal(t, f.items(1, 40), get(t, s, 0, 100))
+
+		del(t, s, 0, 100)
+		require.Empty(t, get(t, s, 0, 100))
+
+		require.Nil(t, first(t, s, 0))
+
+		require.NoError(t, s.Merge(f.items(1, 60)))
+		require.Equal(t, f.items(1, 60), get(t, s, 0, 100))
+	})
+
+	t.Run("ResultIsNotAffectedByChanges", func(t *testing.T) {
+		s := newData(t, 0, 100, f.items(1, 10, 20, 30))
+
+		before := get(t, s, 0, 100)
+
+		require.NoError(t, s.Merge(f.items(2, 20)))
+		del(t, s, 30, 30)
+
+		require.Equal(t, f.items(1, 10, 20, 30), before)
+		require.Equal(t, append(f.items(1, 10), f.items(2, 20)...), get(t, s, 0, 100))
+	})
+
+	t.Run("Iterable", func(t *testing.T) {
+		s := newData(t, 0, 100, f.items(1, 10, 20, 30, 40))
+
+		iterable, ok := s.(sparse.IterableSeriesData[Data, Index])
+		if !ok {
+			t.Skip("storage does not implement IterableSeriesData")
+		}
+
+		for _, period := range [][2]int{{0, 100}, {20, 30}, {15, 35}, {10, 10}, {11, 19}, {41, 100}} {
+			expected := get(t, s, period[0], period[1])
+
+			all, err := iterable.All(f.Index(period[0]), f.Index(period[1]))
+			require.NoError(t, err)
+
+			var forward []Data
+			for item := range all {
+				forward = append(forward, item)
+			}
+
+			require.Equal(t, len(expected), len(forward))
+			if len(expected) != 0 {
+				require.Equal(t, expected, forward)
+			}
+
+			backward, err := iterable.Backward(f.Index(period[0]), f.Index(period[1]))
+			require.NoError(t, err)
+
+			var reversed []Data
+			for item := range backward {
+				reversed = append([]Data{item}, reversed...)
+			}
+
+			require.Equal(t, len(expected), len(reversed))
+			if len(expected) != 0 {
+				require.Equal(t, expected, reversed)
+			}
+		}
+
+		all, err := iterable.All(f.Index(0), f.Index(100))
+		require.NoError(t, err)
+
+		count := 0
+		for range all {
+			count++
+			break
+		}
+		require.Equal(t, 1, count)
+	})
+
+	t.Run("Snapshot", func(t *testing.T) {
+		s := newData(t, 0, 100, f.items(1, 10, 20, 30))
+
+		snapshotable, ok := s.(sparse.SnapshotableSeriesData[Data, Index])
+		if !ok {
+			t.Skip("storage does not implement SnapshotableSeriesData")
+		}
+
+		snapshot, err := snapshotable.Snapshot()
+		require.NoError(t, err)
+
+		require.NoError(t, s.Merge(f.items(2, 20, 40)))
+		del(t, s, 10, 10)
+
+		require.Equal(t, f.items(1, 10, 20, 30), get(t, snapshot, 0, 100))
+		require.Equal(t, f.items(2, 20, 40), get(t, s, 0, 100))
+	})
+}
@@ -0,0 +1,273 @@
+package sparse
+
+import (
+	"container/list"
+	"slices"
+
+	"github.com/pkg/errors"
+)
+
+// Describes how many segments keep their data in storages created by the series data factory.
+// Data of less recently used segments is moved into cold storages (e.g. created by NewFileDataFactory).
+type TieringPolicy[Data any, Index any] struct {
+	// Maximum number of the most recently used segments, which are kept in hot storages.
+	HotSegments int
+	// Creates storages for data of cold segments.
+	Cold SeriesDataFactory[Data, Index]
+}
+
+type seriesTiering[Data any, Index any] struct {
+	policy *TieringPolicy[Data, Index]
+	// Hot segments, the most recently used first.
+	hot      *list.List
+	hotElems map[*SeriesSegment[Data, Index]]*list.Element
+	cold     map[*SeriesSegment[Data, Index]]struct{}
+}
+
+func newSeriesTiering[Data any, Index any](policy *TieringPolicy[Data, Index]) *seriesTiering[Data, Index] {
+	return &seriesTiering[Data, Index]{
+		policy:   policy,
+		hot:      list.New(),
+		hotElems: map[*SeriesSegment[Data, Index]]*list.Element{},
+		cold:     map[*SeriesSegment[Data, Index]]struct{}{},
+	}
+}
+
+// Stops tracking of the segment and reports, whether its data was in cold storage.
+func (t *seriesTiering[Data, Index]) remove(segment *SeriesSegment[Data, Index]) (cold bool) {
+	if elem, ok := t.hotElems[segment]; ok {
+		t.hot.Remove(elem)
+		delete(t.hotElems, segment)
+		return false
+	}
+
+	if _, ok := t.cold[segment]; ok {
+		delete(t.cold, segment)
+		return true
+	}
+
+	return false
+}
+
+// Restores tracking of the segment, which was changed by failed modification.
+func (t *seriesTiering[Data, Index]) reset(segment *SeriesSegment[Data, Index], tracked, cold bool) {
+	t.remove(segment)
+
+	switch {
+	case cold:
+		t.cold[segment] = struct{}{}
+	case tracked:
+		t.hotElems[segment] = t.hot.PushBack(segment)
+	}
+}
+
+// Sets tiering policy, which is enforced immediately. Existing segments are ordered by index, the latest being the most recently used.
+// Reading segment through Get, All, Backward, GetAvailable or Column marks it as used and loads its data back from cold storage.
+// Bounds of all segments stay in memory, so GetPeriod, MissingPeriods and other lookups never access cold storages.
+// Nil disables tiering and loads data of all cold segments back.
+func (s *Series[Data, Index]) SetTiering(policy *TieringPolicy[Data, Index]) error {
+	if policy != nil {
+		if policy.HotSegments <= 0 {
+			return errors.Errorf("invalid tiering hot segments: %v", policy.HotSegments)
+		}
+		if policy.Cold == nil {
+			return errors.New("cold storage factory is not set")
+		}
+	}
+
+	if s.tiering != nil && policy != nil {
+		s.tiering.policy = policy
+
+		if err := s.evictSegments(); err != nil {
+			return err
+		}
+
+		return s.releaseRemovedStorages()
+	}
+
+	if s.tiering != nil {
+		for segment := range s.segments.Values(0) {
+			if _, ok := s.tiering.cold[segment]; ok {
+				if err := s.loadSegment(segment); err != nil {
+					return err
+				}
+			}
+		}
+
+		s.tiering = nil
+
+		return s.releaseRemovedStorages()
+	}
+
+	if policy == nil {
+		return nil
+	}
+
+	s.tiering = newSeriesTiering(policy)
+
+	return s.resetTiering(nil)
+}
+
+// Starts tracking of current segments after they were replaced.
+// Cold storages of previous segments were created by the series, so they are released.
+func (s *Series[Data, Index]) resetTiering(previous *segmentTree[Data, Index]) error {
+	if s.tiering == nil {
+		return nil
+	}
+
+	if previous != nil {
+		previous.root.forEach(func(segment *SeriesSegment[Data, Index]) {
+			if s.tiering.remove(segment) {
+				s.removed = append(s.removed, segment.Data)
+			}
+		})
+	}
+
+	for segment := range s.segments.Values(0) {
+		if err := s.touchSegment(segment); err != nil {
+			return err
+		}
+	}
+
+	return s.releaseRemovedStorages()
+}
+
+// Marks provided segments as the most recently used.
+func (s *Series[Data, Index]) applyTiering(touched ...*SeriesSegment[Data, Index]) error {
+	if s.tiering == nil {
+		return nil
+	}
+
+	for _, segment := range touched {
+		if segment != nil {
+			if err := s.touchSegment(segment); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// Marks segment as the most recently used and loads its data from cold storage.
+func (s *Series[Data, Index]) touchSegment(segment *SeriesSegment[Data, Index]) error {
+	if s.tiering == nil || segment.Empty {
+		return nil
+	}
+
+	if elem, ok := s.tiering.hotElems[segment]; ok {
+		s.tiering.hot.MoveToFront(elem)
+		return nil
+	}
+
+	if _, ok := s.tiering.cold[segment]; ok {
+		if err := s.loadSegment(segment); err != nil {
+			return err
+		}
+	}
+
+	s.tiering.hotElems[segment] = s.tiering.hot.PushFront(segment)
+
+	return s.evictSegments()
+}
+
+// Moves data of the least recently used segments into cold storages.
+func (s *Series[Data, Index]) evictSegments() error {
+	for s.tiering.hot.Len() > s.tiering.policy.HotSegments {
+		elem := s.tiering.hot.Back()
+		segment := elem.Value.(*SeriesSegment[Data, Index])
+
+		if !segment.Empty {
+			hotStorage, err := s.moveSegmentData(segment, s.tiering.policy.Cold)
+			if err != nil {
+				return err
+			}
+
+			s.tiering.cold[segment] = struct{}{}
+
+			if err := s.releaseMovedStorage(hotStorage); err != nil {
+				return err
+			}
+		}
+
+		s.tiering.hot.Remove(elem)
+		delete(s.tiering.hotElems, segment)
+	}
+
+	return nil
+}
+
+func (s *Series[Data, Index]) loadSegment(segment *SeriesSegment[Data, Index]) error {
+	coldStorage, err := s.moveSegmentData(segment, s.dataFactory)
+	if err != nil {
+		return err
+	}
+
+	delete(s.tiering.cold, segment)
+
+	return s.releaseMovedStorage(coldStorage)
+}
+
+// Replaces storage of the segment with storage created by the factory, which contains the same data.
+func (s *Series[Data, Index]) moveSegmentData(segment *SeriesSegment[Data, Index], factory SeriesDataFactory[Data, Index]) (previous SeriesData[Data, Index], _ error) {
+	data, err := segment.GetAll()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get data of segment [ %v ; %v ]", segment.PeriodStart, segment.PeriodEnd)
+	}
+
+	storage, err := factory(s.getIdx, s.idxCmp, segment.PeriodStart, segment.PeriodEnd, slices.Clone(data))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to move data of segment [ %v ; %v ]", segment.PeriodStart, segment.PeriodEnd)
+	}
+
+	s.backupSegment(segment)
+
+	previous = segment.Data
+	segment.Data = storage
+
+	return previous, nil
+}
+
+// Called by segment tree for every removed segment.
+func (s *Series[Data, Index]) segmentRemoved(segment *SeriesSegment[Data, Index]) {
+	if s.tiering != nil {
+		s.tiering.remove(segment)
+	}
+
+	if segment.Data != nil {
+		s.removed = append(s.removed, segment.Data)
+	}
+}
+
+// Releases storage, which was replaced by storage with the same data. During modification it is released
+// only after the modification succeeds.
+func (s *Series[Data, Index]) releaseMovedStorage(storage SeriesData[Data, Index]) error {
+	if s.journal != nil {
+		s.removed = append(s.removed, storage)
+		return nil
+	}
+
+	return releaseStorage(storage)
+}
+
+// Releases storages of removed segments, if they implement RemovableSeriesData.
+func (s *Series[Data, Index]) releaseRemovedStorages() error {
+	for len(s.removed) != 0 {
+		storage := s.removed[0]
+		s.removed = s.removed[1:]
+
+		if err := releaseStorage(storage); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func releaseStorage[Data any, Index any](storage SeriesData[Data, Index]) error {
+	if removable, ok := storage.(RemovableSeriesData); ok {
+		return removable.Remove()
+	}
+
+	return nil
+}
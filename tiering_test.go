@@ -0,0 +1,240 @@
+package sparse_test
+
+import (
+	"math/rand/v2"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/nnikolash/go-sparse"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func intTieringPolicy(dir string, hotSegments int) *sparse.TieringPolicy[int, int] {
+	return &sparse.TieringPolicy[int, int]{
+		HotSegments: hotSegments,
+		Cold:        sparse.NewFileDataFactory(dir, sparse.JSONCodec[int, int]{}, sparse.FileDataOptions{}),
+	}
+}
+
+func isColdSegment(segment *sparse.SeriesSegment[int, int]) bool {
+	_, ok := segment.Data.(*sparse.FileData[int, int])
+	return ok
+}
+
+func countColdSegments(segments []*sparse.SeriesSegment[int, int]) int {
+	count := 0
+	for _, segment := range segments {
+		if isColdSegment(segment) {
+			count++
+		}
+	}
+
+	return count
+}
+
+func countHotSegments(segments []*sparse.SeriesSegment[int, int]) int {
+	count := 0
+	for _, segment := range segments {
+		if !segment.Empty && !isColdSegment(segment) {
+			count++
+		}
+	}
+
+	return count
+}
+
+func countFiles(t *testing.T, dir string) int {
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	return len(entries)
+}
+
+func TestSeries_Tiering(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+
+	series := intSparseSeries()
+	series.SetValidateOnChange(true)
+	require.NoError(t, series.SetTiering(intTieringPolicy(dir, 2)))
+
+	require.NoError(t, series.AddPeriod(0, 10, []int{0, 5, 10}))
+	require.NoError(t, series.AddPeriod(20, 30, []int{25}))
+	require.NoError(t, series.AddPeriod(40, 50, []int{40, 50}))
+	require.NoError(t, series.AddPeriod(60, 70, []int{65}))
+
+	require.Equal(t, 2, countColdSegments(series.Segments()))
+	require.Equal(t, 2, countFiles(t, dir))
+	require.True(t, isColdSegment(series.GetPeriod(0, 10)))
+	require.True(t, isColdSegment(series.GetPeriod(20, 30)))
+
+	// Bookkeeping does not load cold segments
+	require.Equal(t, []sparse.PeriodBounds[int]{{PeriodStart: 10, PeriodEnd: 20}, {PeriodStart: 30, PeriodEnd: 40}, {PeriodStart: 50, PeriodEnd: 60}}, series.MissingPeriods(0, 70))
+	require.True(t, isColdSegment(series.GetPeriod(20, 30)))
+
+	// Reading loads the segment back and moves the least recently used one to cold storage
+	require.Equal(t, []int{5, 10}, must2(series.Get(3, 10)))
+	require.False(t, isColdSegment(series.GetPeriod(0, 10)))
+	require.True(t, isColdSegment(series.GetPeriod(40, 50)))
+	require.Equal(t, 2, countColdSegments(series.Segments()))
+	require.Equal(t, 2, countFiles(t, dir))
+
+	// Merged cold segments release their files
+	require.NoError(t, series.AddPeriod(25, 45, []int{35}))
+	require.Equal(t, []int{35, 50}, must2(series.Get(20, 50)))
+	require.Equal(t, 1, countColdSegments(series.Segments()))
+	require.True(t, isColdSegment(series.GetPeriod(60, 70)))
+	require.Equal(t, 1, countFiles(t, dir))
+
+	require.NoError(t, series.DeletePeriod(55, 80))
+	require.Equal(t, 0, countFiles(t, dir))
+
+	require.NoError(t, series.AddPeriod(80, 90, []int{85}))
+	require.Equal(t, 1, countFiles(t, dir))
+	require.NoError(t, series.SetRetention(sparse.RetainSegments[int](1)))
+	require.Equal(t, 0, countFiles(t, dir))
+
+	require.NoError(t, series.AddPeriod(100, 110, []int{105}))
+	require.NoError(t, series.AddPeriod(120, 130, []int{125}))
+	require.NoError(t, series.SetRetention(nil))
+	require.NoError(t, series.AddPeriod(140, 150, []int{145}))
+	require.NoError(t, series.AddPeriod(160, 170, []int{165}))
+	require.True(t, isColdSegment(series.GetPeriod(120, 130)))
+	require.Equal(t, 1, countFiles(t, dir))
+
+	// Disabling tiering loads everything back
+	require.NoError(t, series.SetTiering(nil))
+	require.Equal(t, 0, countColdSegments(series.Segments()))
+	require.Equal(t, 0, countFiles(t, dir))
+	require.Equal(t, []int{125}, must2(series.Get(120, 130)))
+}
+
+func TestSeries_TieringEnableAndRestore(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+
+	series := intSparseSeries()
+	require.NoError(t, series.AddPeriod(0, 10, []int{5}))
+	require.NoError(t, series.AddPeriod(20, 30, []int{25}))
+	require.NoError(t, series.AddPeriod(40, 50, []int{45}))
+
+	// Earlier segments are considered less recently used
+	require.NoError(t, series.SetTiering(intTieringPolicy(dir, 1)))
+	require.True(t, isColdSegment(series.GetPeriod(0, 10)))
+	require.True(t, isColdSegment(series.GetPeriod(20, 30)))
+	require.False(t, isColdSegment(series.GetPeriod(40, 50)))
+	require.Equal(t, 2, countFiles(t, dir))
+
+	snapshot, err := series.Snapshot()
+	require.NoError(t, err)
+
+	require.NoError(t, series.SetTiering(intTieringPolicy(dir, 3)))
+	require.Equal(t, 2, countColdSegments(series.Segments()))
+
+	restored := intSparseSeries()
+	require.NoError(t, restored.AddPeriod(60, 70, []int{65}))
+	b, err := restored.MarshalBinary()
+	require.NoError(t, err)
+
+	// Restoring releases files of replaced segments
+	require.NoError(t, series.UnmarshalBinary(b))
+	require.Equal(t, 0, countFiles(t, dir))
+	require.Equal(t, []int{65}, must2(series.Get(60, 70)))
+
+	require.Equal(t, []int{5}, must2(snapshot.Get(0, 10)))
+	require.Equal(t, []int{45}, must2(snapshot.Get(40, 50)))
+}
+
+func TestSeries_TieringInvalidPolicy(t *testing.T) {
+	t.Parallel()
+
+	series := intSparseSeries()
+	require.Error(t, series.SetTiering(intTieringPolicy(t.TempDir(), 0)))
+	require.Error(t, series.SetTiering(&sparse.TieringPolicy[int, int]{HotSegments: 1}))
+	require.NoError(t, series.SetTiering(nil))
+}
+
+func TestSeries_TieringMatchesPlainSeries(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+
+	tiered := intSparseSeries()
+	tiered.SetValidateOnChange(true)
+	require.NoError(t, tiered.SetTiering(intTieringPolicy(dir, 2)))
+
+	plain := intSparseSeries()
+
+	for i := 0; i < 300; i++ {
+		start := rand.IntN(200)
+		end := start + rand.IntN(15)
+
+		switch rand.IntN(4) {
+		case 0:
+			require.NoError(t, tiered.DeletePeriod(start, end))
+			require.NoError(t, plain.DeletePeriod(start, end))
+		case 1:
+			tieredAvailable, tieredMissing, err := tiered.GetAvailable(start, end)
+			require.NoError(t, err)
+			plainAvailable, plainMissing, err := plain.GetAvailable(start, end)
+			require.NoError(t, err)
+			require.Equal(t, plainAvailable, tieredAvailable, "step %v", i)
+			require.Equal(t, plainMissing, tieredMissing, "step %v", i)
+		default:
+			var data []int
+			for idx := start; idx <= end; idx++ {
+				if rand.IntN(2) == 0 {
+					data = append(data, idx)
+				}
+			}
+
+			require.NoError(t, tiered.AddPeriod(start, end, data))
+			require.NoError(t, plain.AddPeriod(start, end, data))
+		}
+
+		segments := tiered.Segments()
+		require.Equal(t, len(plain.Segments()), len(segments), "step %v", i)
+		require.LessOrEqual(t, countHotSegments(segments), 2, "step %v", i)
+		require.Equal(t, countColdSegments(segments), countFiles(t, dir), "step %v", i)
+
+		for _, segment := range plain.Segments() {
+			require.Equal(t, must2(plain.Get(segment.PeriodStart, segment.PeriodEnd)), must2(tiered.Get(segment.PeriodStart, segment.PeriodEnd)), "step %v", i)
+		}
+	}
+}
+
+func TestConcurrentSeries_Tiering(t *testing.T) {
+	t.Parallel()
+
+	series := sparse.NewConcurrentSeries[int, int](
+		sparse.NewArrayData,
+		func(data *int) int { return *data },
+		func(idx1, idx2 int) int { return idx1 - idx2 },
+		nil,
+	)
+	require.NoError(t, series.SetTiering(intTieringPolicy(t.TempDir(), 1)))
+
+	for i := 0; i < 5; i++ {
+		require.NoError(t, series.AddPeriod(i*10, i*10+5, []int{i*10 + 1}))
+	}
+
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for i := 0; i < 50; i++ {
+				segment := rand.IntN(5)
+				data, err := series.Get(segment*10, segment*10+5)
+				assert.NoError(t, err)
+				assert.Equal(t, []int{segment*10 + 1}, data)
+			}
+		}()
+	}
+
+	wg.Wait()
+}
@@ -0,0 +1,62 @@
+package sparse
+
+import (
+	"iter"
+
+	"github.com/pkg/errors"
+)
+
+// Checks that segments are sorted, do not overlap and are not continuous with each other, and each of them is valid.
+func (s *Series[Data, Index]) Validate() error {
+	return s.validateSegments(s.segments.Values(0), true)
+}
+
+// Enables validation of the series after each modification and full validation of restored data.
+// Intended for debugging, because it visits all the data.
+func (s *Series[Data, Index]) SetValidateOnChange(validate bool) {
+	s.validateOnChange = validate
+}
+
+func (s *Series[Data, Index]) validateAfterChange() error {
+	if !s.validateOnChange {
+		return nil
+	}
+
+	return s.Validate()
+}
+
+// If full is not set, only the first and the last items of each segment are checked.
+func (s *Series[Data, Index]) validateSegments(segments iter.Seq[*SeriesSegment[Data, Index]], full bool) error {
+	var prev *SeriesSegment[Data, Index]
+	i := 0
+
+	for segment := range segments {
+		if err := segment.validateData(full); err != nil {
+			return errors.Wrapf(err, "invalid segment %v", i)
+		}
+
+		if prev != nil {
+			if s.idxCmp(prev.PeriodEnd, segment.PeriodStart) >= 0 {
+				return errors.WithStack(&StorageCorruptedError[Index]{
+					PeriodStart: prev.PeriodStart,
+					PeriodEnd:   segment.PeriodEnd,
+					Err: errors.Errorf("segments %v and %v are not sorted or overlap: [ %v ; %v ], [ %v ; %v ]",
+						i-1, i, prev.PeriodStart, prev.PeriodEnd, segment.PeriodStart, segment.PeriodEnd),
+				})
+			}
+			if s.areContinuous(prev.PeriodEnd, segment.PeriodStart) {
+				return errors.WithStack(&StorageCorruptedError[Index]{
+					PeriodStart: prev.PeriodStart,
+					PeriodEnd:   segment.PeriodEnd,
+					Err: errors.Errorf("segments %v and %v are continuous, but not merged: [ %v ; %v ], [ %v ; %v ]",
+						i-1, i, prev.PeriodStart, prev.PeriodEnd, segment.PeriodStart, segment.PeriodEnd),
+				})
+			}
+		}
+
+		prev = segment
+		i++
+	}
+
+	return nil
+}
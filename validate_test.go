@@ -0,0 +1,125 @@
+package sparse_test
+
+import (
+	"testing"
+
+	"github.com/nnikolash/go-sparse"
+	"github.com/stretchr/testify/require"
+)
+
+func arrayDataOf(data ...int) sparse.SeriesData[int, int] {
+	return must2(sparse.NewArrayData(
+		func(data *int) int { return *data },
+		func(idx1, idx2 int) int { return idx1 - idx2 },
+		0, 0, data,
+	))
+}
+
+func segmentFields(periodStart, periodEnd int, empty bool, data ...int) *sparse.SeriesSegmentFields[int, int] {
+	return &sparse.SeriesSegmentFields[int, int]{
+		PeriodBounds: sparse.PeriodBounds[int]{PeriodStart: periodStart, PeriodEnd: periodEnd},
+		Data:         arrayDataOf(data...),
+		Empty:        empty,
+	}
+}
+
+func TestSeries_Validate(t *testing.T) {
+	t.Parallel()
+
+	series := intSparseSeries()
+	require.NoError(t, series.Validate())
+
+	require.NoError(t, series.AddPeriod(10, 20, []int{10, 15}))
+	require.NoError(t, series.AddPeriod(30, 40, nil))
+	require.NoError(t, series.AddPeriod(22, 25, []int{23}))
+	require.NoError(t, series.Validate())
+
+	for _, segment := range series.GetAllSegments() {
+		require.NoError(t, segment.Validate())
+	}
+
+	series.GetAllSegments()[1].Empty = true
+	require.Error(t, series.Validate())
+}
+
+func TestSeries_ValidateOnRestore(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string][]*sparse.SeriesSegmentFields[int, int]{
+		"overlapping":       {segmentFields(10, 20, false, 10), segmentFields(20, 30, false, 25)},
+		"not sorted":        {segmentFields(30, 40, false, 35), segmentFields(10, 20, false, 15)},
+		"continuous":        {segmentFields(10, 20, false, 10), segmentFields(21, 30, false, 25)},
+		"marked as empty":   {segmentFields(10, 20, true, 15)},
+		"marked as filled":  {segmentFields(10, 20, false)},
+		"data before start": {segmentFields(10, 20, false, 5, 15)},
+		"data after end":    {segmentFields(10, 20, false, 15, 25)},
+		"inverted bounds":   {segmentFields(20, 10, false, 15)},
+		"no storage":        {{PeriodBounds: sparse.PeriodBounds[int]{PeriodStart: 10, PeriodEnd: 20}, Empty: true}},
+	}
+
+	for name, segments := range cases {
+		series := intSparseSeries()
+		require.NoError(t, series.AddPeriod(0, 5, []int{1}))
+
+		err := series.Restore(&sparse.SeriesState[int, int]{Segments: segments})
+		require.Error(t, err, name)
+
+		// Series is not modified on failed restore
+		require.Len(t, series.Segments(), 1, name)
+	}
+
+	series := intSparseSeries()
+	err := series.Restore(&sparse.SeriesState[int, int]{Segments: []*sparse.SeriesSegmentFields[int, int]{
+		segmentFields(10, 20, false, 10, 20),
+		segmentFields(22, 30, true),
+	}})
+	require.NoError(t, err)
+	require.Len(t, series.Segments(), 2)
+}
+
+type getCountingData struct {
+	sparse.SeriesData[int, int]
+	gets int
+}
+
+func (s *getCountingData) Get(periodStart, periodEnd int) ([]int, error) {
+	s.gets++
+	return s.SeriesData.Get(periodStart, periodEnd)
+}
+
+func TestSeries_ValidateOnRestoreReadsOnlyBounds(t *testing.T) {
+	t.Parallel()
+
+	unsorted := &getCountingData{SeriesData: arrayDataOf(10, 18, 12, 20)}
+	state := &sparse.SeriesState[int, int]{Segments: []*sparse.SeriesSegmentFields[int, int]{
+		{PeriodBounds: sparse.PeriodBounds[int]{PeriodStart: 10, PeriodEnd: 20}, Data: unsorted},
+	}}
+
+	series := intSparseSeries()
+	require.NoError(t, series.Restore(state))
+	require.Zero(t, unsorted.gets)
+
+	series = intSparseSeries()
+	series.SetValidateOnChange(true)
+	require.Error(t, series.Restore(state))
+	require.NotZero(t, unsorted.gets)
+}
+
+func TestSeries_ValidateOnChange(t *testing.T) {
+	t.Parallel()
+
+	series := intSparseSeries()
+	series.SetValidateOnChange(true)
+
+	require.NoError(t, series.AddPeriod(10, 20, []int{10, 15}))
+	require.NoError(t, series.AddPeriod(30, 40, []int{35}))
+	require.NoError(t, series.DeletePeriod(12, 32))
+	require.NoError(t, series.SetRetention(sparse.RetainSegments[int](1)))
+	require.NoError(t, series.SetRetention(nil))
+
+	series.GetAllSegments()[0].Empty = true
+	require.Error(t, series.AddPeriod(50, 60, nil))
+
+	series.SetValidateOnChange(false)
+	require.NoError(t, series.AddPeriod(70, 80, nil))
+}
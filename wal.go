@@ -0,0 +1,356 @@
+package sparse
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	walRecordCheckpoint = 1
+	walRecordAddPeriod  = 2
+	walRecordDelete     = 3
+	walRecordRollback   = 4
+)
+
+type WALOptions struct {
+	// Sync file after each write.
+	Sync bool
+}
+
+// Write-ahead log of series modifications. Each record has sequence number, which is never reset,
+// so records already included into snapshot are skipped on replay. Modification, which failed after being recorded,
+// is followed by rollback record, so it is skipped on replay too.
+type WAL[Data any, Index any] struct {
+	path    string
+	codec   Codec[Data, Index]
+	opts    WALOptions
+	lastSeq uint64
+}
+
+type walRecord[Data any, Index any] struct {
+	recordType  byte
+	seq         uint64
+	periodStart Index
+	periodEnd   Index
+	data        []Data
+	rollbackSeq uint64
+}
+
+// Opens log file or creates it, if it does not exist. If the file ends with torn or corrupted record,
+// it is truncated to the last valid record.
+func OpenWAL[Data any, Index any](path string, codec Codec[Data, Index], opts WALOptions) (*WAL[Data, Index], error) {
+	w := &WAL[Data, Index]{
+		path:  path,
+		codec: codec,
+		opts:  opts,
+	}
+
+	if err := appendToFile(path, nil, false); err != nil {
+		return nil, err
+	}
+
+	_, err := recoverRecords(path, func(offset int64, payload []byte) error {
+		seq, n := binary.Uvarint(payload[min(1, len(payload)):])
+		if n <= 0 {
+			return errors.Errorf("invalid record at %v in log %v", offset, path)
+		}
+
+		w.lastSeq = seq
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return w, nil
+}
+
+func (w *WAL[Data, Index]) Path() string {
+	return w.path
+}
+
+func (w *WAL[Data, Index]) LastSeq() uint64 {
+	return w.lastSeq
+}
+
+func (w *WAL[Data, Index]) logAddPeriod(periodStart, periodEnd Index, data []Data) error {
+	payload, err := w.encodePeriod(walRecordAddPeriod, periodStart, periodEnd)
+	if err != nil {
+		return err
+	}
+
+	encodedData, err := w.codec.EncodeData(data)
+	if err != nil {
+		return err
+	}
+
+	return w.write(append(payload, encodedData...))
+}
+
+func (w *WAL[Data, Index]) logDelete(periodStart, periodEnd Index) error {
+	payload, err := w.encodePeriod(walRecordDelete, periodStart, periodEnd)
+	if err != nil {
+		return err
+	}
+
+	return w.write(payload)
+}
+
+func (w *WAL[Data, Index]) logRollback(seq uint64) error {
+	payload := binary.AppendUvarint([]byte{walRecordRollback}, w.lastSeq+1)
+	payload = binary.AppendUvarint(payload, seq)
+
+	return w.write(payload)
+}
+
+func (w *WAL[Data, Index]) encodePeriod(recordType byte, periodStart, periodEnd Index) ([]byte, error) {
+	encodedStart, err := w.codec.EncodeIndex(periodStart)
+	if err != nil {
+		return nil, err
+	}
+
+	encodedEnd, err := w.codec.EncodeIndex(periodEnd)
+	if err != nil {
+		return nil, err
+	}
+
+	payload := []byte{recordType}
+	payload = binary.AppendUvarint(payload, w.lastSeq+1)
+	payload = appendBytes(payload, encodedStart)
+	payload = appendBytes(payload, encodedEnd)
+
+	return payload, nil
+}
+
+func (w *WAL[Data, Index]) write(payload []byte) error {
+	if err := appendToFile(w.path, appendRecord(nil, payload), w.opts.Sync); err != nil {
+		return err
+	}
+
+	w.lastSeq++
+
+	return nil
+}
+
+// Removes all records. Checkpoint record is kept to preserve sequence number.
+func (w *WAL[Data, Index]) truncate() error {
+	payload := binary.AppendUvarint([]byte{walRecordCheckpoint}, w.lastSeq)
+	return replaceFile(w.path, appendRecord(nil, payload), w.opts.Sync)
+}
+
+func (w *WAL[Data, Index]) readRecords(f func(record *walRecord[Data, Index]) error) error {
+	_, err := readRecords(w.path, func(offset int64, payload []byte) error {
+		record, err := w.decodeRecord(payload)
+		if err != nil {
+			return errors.Wrapf(err, "failed to decode record at %v in log %v", offset, w.path)
+		}
+
+		return f(record)
+	})
+
+	return err
+}
+
+func (w *WAL[Data, Index]) decodeRecord(payload []byte) (*walRecord[Data, Index], error) {
+	r := bytes.NewReader(payload)
+
+	recordType, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+
+	record := &walRecord[Data, Index]{recordType: recordType}
+
+	if record.seq, err = binary.ReadUvarint(r); err != nil {
+		return nil, err
+	}
+
+	if recordType == walRecordCheckpoint {
+		return record, nil
+	}
+	if recordType == walRecordRollback {
+		if record.rollbackSeq, err = binary.ReadUvarint(r); err != nil {
+			return nil, err
+		}
+		return record, nil
+	}
+	if recordType != walRecordAddPeriod && recordType != walRecordDelete {
+		return nil, errors.Errorf("unknown record type %v", recordType)
+	}
+
+	encodedStart, err := readBytes(r)
+	if err != nil {
+		return nil, err
+	}
+	if record.periodStart, err = w.codec.DecodeIndex(encodedStart); err != nil {
+		return nil, err
+	}
+
+	encodedEnd, err := readBytes(r)
+	if err != nil {
+		return nil, err
+	}
+	if record.periodEnd, err = w.codec.DecodeIndex(encodedEnd); err != nil {
+		return nil, err
+	}
+
+	if recordType == walRecordAddPeriod {
+		if record.data, err = w.codec.DecodeData(payload[len(payload)-r.Len():]); err != nil {
+			return nil, err
+		}
+	}
+
+	return record, nil
+}
+
+// Sets write-ahead log, which records each AddPeriod and DeletePeriod before applying it.
+// Nil disables logging. Retention is not logged, so it must be set before replay the same way.
+func (s *Series[Data, Index]) SetWAL(wal *WAL[Data, Index]) {
+	s.wal = wal
+}
+
+// Writes snapshot of the series encoded with codec of write-ahead log and removes all records from the log.
+func (s *Series[Data, Index]) Checkpoint(snapshotPath string) error {
+	if s.wal == nil {
+		return errors.New("write-ahead log is not set")
+	}
+
+	b, err := s.marshalBinary(s.wal.codec)
+	if err != nil {
+		return err
+	}
+
+	payload := binary.AppendUvarint(nil, s.wal.lastSeq)
+	payload = append(payload, b...)
+
+	if err := replaceFile(snapshotPath, appendRecord(nil, payload), true); err != nil {
+		return err
+	}
+
+	return s.wal.truncate()
+}
+
+// Replaces content of the series with the snapshot and applies records of write-ahead log, which were written after it.
+// Missing snapshot file is treated as empty series. Snapshot, which is newer than the log, means that records
+// were lost, so it is reported as error. After replay the log is attached to the series.
+func (s *Series[Data, Index]) Replay(snapshotPath string, wal *WAL[Data, Index]) error {
+	rolledBack := map[uint64]struct{}{}
+
+	err := wal.readRecords(func(record *walRecord[Data, Index]) error {
+		if record.recordType == walRecordRollback {
+			rolledBack[record.rollbackSeq] = struct{}{}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	snapshotSeq, err := s.loadCheckpoint(snapshotPath, wal)
+	if err != nil {
+		return err
+	}
+
+	s.wal = nil
+
+	err = wal.readRecords(func(record *walRecord[Data, Index]) error {
+		if record.seq <= snapshotSeq {
+			return nil
+		}
+		if _, ok := rolledBack[record.seq]; ok {
+			return nil
+		}
+
+		switch record.recordType {
+		case walRecordAddPeriod:
+			return errors.Wrapf(s.AddPeriod(record.periodStart, record.periodEnd, record.data), "failed to replay record %v", record.seq)
+		case walRecordDelete:
+			return errors.Wrapf(s.DeletePeriod(record.periodStart, record.periodEnd), "failed to replay record %v", record.seq)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	s.wal = wal
+
+	return nil
+}
+
+func (s *Series[Data, Index]) loadCheckpoint(snapshotPath string, wal *WAL[Data, Index]) (seq uint64, _ error) {
+	var payload []byte
+
+	_, err := readRecords(snapshotPath, func(offset int64, p []byte) error {
+		payload = p
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return 0, s.Restore(&SeriesState[Data, Index]{})
+		}
+		return 0, err
+	}
+
+	if payload == nil {
+		return 0, errors.Errorf("snapshot %v is corrupted", snapshotPath)
+	}
+
+	seq, n := binary.Uvarint(payload)
+	if n <= 0 {
+		return 0, errors.Errorf("snapshot %v is corrupted", snapshotPath)
+	}
+	if seq > wal.lastSeq {
+		return 0, errors.Errorf("snapshot %v includes record %v, but log ends at record %v", snapshotPath, seq, wal.lastSeq)
+	}
+
+	if err := s.unmarshalBinary(payload[n:], wal.codec); err != nil {
+		return 0, err
+	}
+
+	return seq, nil
+}
+
+func (s *Series[Data, Index]) logAddPeriod(periodStart, periodEnd Index, data []Data) error {
+	if s.wal == nil {
+		return nil
+	}
+
+	// Invalid modifications must not get into the log
+	segment := NewSeriesSegment(s.dataFactory, s.getIdx, s.idxCmp, s.areContinuous)
+	if err := segment.validateDataBounds(periodStart, periodEnd, data); err != nil {
+		return err
+	}
+
+	return s.wal.logAddPeriod(periodStart, periodEnd, data)
+}
+
+func (s *Series[Data, Index]) logDelete(periodStart, periodEnd Index) error {
+	if s.wal == nil {
+		return nil
+	}
+
+	if s.idxCmp(periodStart, periodEnd) > 0 {
+		return errors.Errorf("requested period start is greater than period end: %v > %v", periodStart, periodEnd)
+	}
+
+	return s.wal.logDelete(periodStart, periodEnd)
+}
+
+// Marks the last logged modification as rolled back, because it failed with err.
+func (s *Series[Data, Index]) rollbackLogged(err error) error {
+	if s.wal == nil {
+		return err
+	}
+
+	if rollbackErr := s.wal.logRollback(s.wal.lastSeq); rollbackErr != nil {
+		return errors.Wrapf(err, "failed to roll back logged record: %v", rollbackErr)
+	}
+
+	return err
+}
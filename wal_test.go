@@ -0,0 +1,235 @@
+package sparse_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/nnikolash/go-sparse"
+	"github.com/stretchr/testify/require"
+)
+
+func openIntWAL(t *testing.T, path string) *sparse.WAL[int, int] {
+	wal, err := sparse.OpenWAL(path, sparse.JSONCodec[int, int]{}, sparse.WALOptions{})
+	require.NoError(t, err)
+	return wal
+}
+
+func TestSeries_WALReplay(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	walPath := filepath.Join(dir, "series.wal")
+	snapshotPath := filepath.Join(dir, "series.snapshot")
+
+	series := intSparseSeries()
+	series.SetWAL(openIntWAL(t, walPath))
+
+	require.NoError(t, series.AddPeriod(10, 20, []int{10, 15, 20}))
+	require.NoError(t, series.AddPeriod(30, 40, nil))
+	require.NoError(t, series.DeletePeriod(12, 17))
+	require.Error(t, series.AddPeriod(50, 40, nil))
+	require.Error(t, series.DeletePeriod(50, 40))
+
+	replayed := intSparseSeries()
+	wal := openIntWAL(t, walPath)
+	require.Equal(t, uint64(3), wal.LastSeq())
+	require.NoError(t, replayed.Replay(snapshotPath, wal))
+	requireSameSeries(t, series, replayed)
+
+	// Replayed series continues writing into the log
+	require.NoError(t, replayed.AddPeriod(50, 60, []int{55}))
+	require.NoError(t, series.AddPeriod(50, 60, []int{55}))
+
+	replayed = intSparseSeries()
+	require.NoError(t, replayed.Replay(snapshotPath, openIntWAL(t, walPath)))
+	requireSameSeries(t, series, replayed)
+}
+
+func TestSeries_WALCheckpoint(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	walPath := filepath.Join(dir, "series.wal")
+	snapshotPath := filepath.Join(dir, "series.snapshot")
+
+	series := intSparseSeries()
+	require.Error(t, series.Checkpoint(snapshotPath))
+
+	series.SetWAL(openIntWAL(t, walPath))
+
+	for i := 0; i < 100; i++ {
+		require.NoError(t, series.AddPeriod(i*10, i*10+5, []int{i * 10}))
+	}
+
+	walBeforeCheckpoint, err := os.ReadFile(walPath)
+	require.NoError(t, err)
+
+	require.NoError(t, series.Checkpoint(snapshotPath))
+
+	walAfterCheckpoint, err := os.ReadFile(walPath)
+	require.NoError(t, err)
+	require.Less(t, len(walAfterCheckpoint), len(walBeforeCheckpoint)/50)
+
+	require.NoError(t, series.DeletePeriod(0, 500))
+	require.NoError(t, series.AddPeriod(1000, 1010, []int{1005}))
+
+	replayed := intSparseSeries()
+	wal := openIntWAL(t, walPath)
+	require.Equal(t, uint64(102), wal.LastSeq())
+	require.NoError(t, replayed.Replay(snapshotPath, wal))
+	requireSameSeries(t, series, replayed)
+
+	// Crash after writing snapshot, but before truncating log: records included into snapshot are skipped.
+	walBeforeCheckpoint, err = os.ReadFile(walPath)
+	require.NoError(t, err)
+	require.NoError(t, series.Checkpoint(snapshotPath))
+	require.NoError(t, os.WriteFile(walPath, walBeforeCheckpoint, 0o644))
+
+	replayed = intSparseSeries()
+	require.NoError(t, replayed.Replay(snapshotPath, openIntWAL(t, walPath)))
+	requireSameSeries(t, series, replayed)
+}
+
+func TestSeries_WALTornWrite(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	walPath := filepath.Join(dir, "series.wal")
+	snapshotPath := filepath.Join(dir, "series.snapshot")
+
+	series := intSparseSeries()
+	series.SetWAL(openIntWAL(t, walPath))
+
+	require.NoError(t, series.AddPeriod(10, 20, []int{10, 15, 20}))
+
+	stat, err := os.Stat(walPath)
+	require.NoError(t, err)
+
+	require.NoError(t, series.AddPeriod(30, 40, []int{35}))
+	require.NoError(t, os.Truncate(walPath, stat.Size()+3))
+
+	replayed := intSparseSeries()
+	require.NoError(t, replayed.Replay(snapshotPath, openIntWAL(t, walPath)))
+	require.Len(t, replayed.Segments(), 1)
+	require.Equal(t, []int{10, 15, 20}, must2(replayed.Get(10, 20)))
+
+	// Log is usable after recovery
+	require.NoError(t, replayed.AddPeriod(50, 60, []int{55}))
+	replayedAgain := intSparseSeries()
+	require.NoError(t, replayedAgain.Replay(snapshotPath, openIntWAL(t, walPath)))
+	requireSameSeries(t, replayed, replayedAgain)
+}
+
+func TestSeries_WALCorruptedSnapshot(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	walPath := filepath.Join(dir, "series.wal")
+	snapshotPath := filepath.Join(dir, "series.snapshot")
+
+	series := intSparseSeries()
+	series.SetWAL(openIntWAL(t, walPath))
+	require.NoError(t, series.AddPeriod(10, 20, []int{10, 15, 20}))
+	require.NoError(t, series.Checkpoint(snapshotPath))
+
+	b, err := os.ReadFile(snapshotPath)
+	require.NoError(t, err)
+	b[len(b)-1] ^= 0xFF
+	require.NoError(t, os.WriteFile(snapshotPath, b, 0o644))
+
+	require.Error(t, intSparseSeries().Replay(snapshotPath, openIntWAL(t, walPath)))
+}
+
+func TestSeries_WALFailedModificationIsRolledBack(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	walPath := filepath.Join(dir, "series.wal")
+	snapshotPath := filepath.Join(dir, "series.snapshot")
+
+	newSeries := func() *sparse.Series[int, int] {
+		return sparse.NewSeries(
+			sparse.NewArrayDataFactory[int, int](sparse.ArrayDataOptions[int]{RejectDuplicates: true}),
+			func(data *int) int { return *data },
+			func(idx1, idx2 int) int { return idx1 - idx2 },
+			nil,
+		)
+	}
+
+	series := newSeries()
+	series.SetWAL(openIntWAL(t, walPath))
+
+	require.NoError(t, series.AddPeriod(10, 20, []int{10, 20}))
+	// Data passes bounds validation, but is rejected by the storage after being logged
+	require.ErrorIs(t, series.AddPeriod(30, 40, []int{35, 35}), sparse.ErrDuplicateIndex)
+	require.NoError(t, series.AddPeriod(50, 60, []int{55}))
+
+	replayed := newSeries()
+	wal := openIntWAL(t, walPath)
+	require.Equal(t, uint64(4), wal.LastSeq())
+	require.NoError(t, replayed.Replay(snapshotPath, wal))
+	requireSameSeries(t, series, replayed)
+
+	require.NoError(t, replayed.AddPeriod(30, 40, []int{35}))
+	replayedAgain := newSeries()
+	require.NoError(t, replayedAgain.Replay(snapshotPath, openIntWAL(t, walPath)))
+	requireSameSeries(t, replayed, replayedAgain)
+}
+
+func TestSeries_WALSnapshotAheadOfLog(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	walPath := filepath.Join(dir, "series.wal")
+	snapshotPath := filepath.Join(dir, "series.snapshot")
+
+	series := intSparseSeries()
+	series.SetWAL(openIntWAL(t, walPath))
+	require.NoError(t, series.AddPeriod(10, 20, []int{10, 15, 20}))
+	require.NoError(t, series.Checkpoint(snapshotPath))
+	require.NoError(t, series.AddPeriod(30, 40, []int{35}))
+
+	// Log is lost, so records written after snapshot can't be told apart from the ones included into it
+	require.NoError(t, os.Remove(walPath))
+
+	replayed := intSparseSeries()
+	require.NoError(t, replayed.AddPeriod(50, 60, []int{55}))
+	require.Error(t, replayed.Replay(snapshotPath, openIntWAL(t, walPath)))
+	require.Equal(t, []int{55}, must2(replayed.Get(50, 60)))
+}
+
+type failingDataCodec struct {
+	sparse.JSONCodec[int, int]
+}
+
+func (failingDataCodec) EncodeData(data []int) ([]byte, error) {
+	return nil, errors.New("encoding is not supported")
+}
+
+func (failingDataCodec) DecodeData(b []byte) ([]int, error) {
+	return nil, errors.New("decoding is not supported")
+}
+
+func TestSeries_WALCheckpointUsesLogCodec(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	walPath := filepath.Join(dir, "series.wal")
+	snapshotPath := filepath.Join(dir, "series.snapshot")
+
+	series := intSparseSeries()
+	series.SetCodec(failingDataCodec{})
+	series.SetWAL(openIntWAL(t, walPath))
+
+	require.NoError(t, series.AddPeriod(10, 20, []int{10, 20}))
+	require.Error(t, func() error { _, err := series.MarshalBinary(); return err }())
+	require.NoError(t, series.Checkpoint(snapshotPath))
+	require.NoError(t, series.AddPeriod(30, 40, []int{35}))
+
+	replayed := intSparseSeries()
+	replayed.SetCodec(failingDataCodec{})
+	require.NoError(t, replayed.Replay(snapshotPath, openIntWAL(t, walPath)))
+	requireSameSeries(t, series, replayed)
+}